@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Base26 armor carries arbitrary bytes in letters A-Z, so that any file can be enciphered.
+// Each 4 bytes, read as a big-endian number, become 7 letters, most significant first; a final
+// 1, 2 or 3 bytes become 2, 4 or 6 letters, so the length of the text tells where it ends.
+const (
+	armorChunk   = 4 // Bytes in a full chunk
+	armorLetters = 7 // Letters for a full chunk
+)
+
+// armorLength gives the letters needed for a chunk of n bytes, from 0 to armorChunk.
+var armorLength = [armorChunk + 1]int{0, 2, 4, 6, 7}
+
+// base26Encode converts data to letters.
+func base26Encode(data []byte) string {
+	out := make([]byte, 0, (len(data)+armorChunk-1)/armorChunk*armorLetters)
+	for len(data) > 0 {
+		n := armorChunk
+		if len(data) < n {
+			n = len(data)
+		}
+		var v uint64
+		for _, b := range data[:n] {
+			v = v<<8 | uint64(b)
+		}
+		letters := make([]byte, armorLength[n])
+		for i := len(letters) - 1; i >= 0; i-- {
+			letters[i] = 'A' + byte(v%26)
+			v /= 26
+		}
+		out = append(out, letters...)
+		data = data[n:]
+	}
+	return string(out)
+}
+
+// base26Decode converts letters made by base26Encode back to bytes. Spaces and newlines are
+// ignored, and lower case is accepted.
+func base26Decode(text string) ([]byte, error) {
+	letters := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			letters = append(letters, c-'A')
+		case c >= 'a' && c <= 'z':
+			letters = append(letters, c-'a')
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+		default:
+			return nil, fmt.Errorf("character %q at offset %d is not a letter", c, i)
+		}
+	}
+
+	out := make([]byte, 0, len(letters)/armorLetters*armorChunk+armorChunk)
+	for pos := 0; pos < len(letters); {
+		k := armorLetters
+		if len(letters)-pos < k {
+			k = len(letters) - pos
+		}
+		n := 0
+		for n <= armorChunk && armorLength[n] != k {
+			n++
+		}
+		if n > armorChunk {
+			return nil, fmt.Errorf("armor ends with %d letters, which cannot encode whole bytes", k)
+		}
+		var v uint64
+		for _, l := range letters[pos : pos+k] {
+			v = v*26 + uint64(l)
+		}
+		if v>>uint(8*n) != 0 {
+			return nil, fmt.Errorf("letters %d to %d are out of range for %d bytes", pos, pos+k, n)
+		}
+		for i := n - 1; i >= 0; i-- {
+			out = append(out, byte(v>>uint(8*i)))
+		}
+		pos += k
+	}
+	return out, nil
+}
+
+// groupLetters splits letters into groups of five, ten groups a line, in the manner of
+// enciphered traffic. Spaces and newlines do not step the machine, so the grouping does not
+// affect encipherment.
+func groupLetters(letters string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(letters); i += 5 {
+		if i > 0 {
+			if i%50 == 0 {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		end := i + 5
+		if end > len(letters) {
+			end = len(letters)
+		}
+		b.WriteString(letters[i:end])
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// encipherBinary armors data and enciphers it, returning grouped ciphertext.
+func (m *Machine) encipherBinary(data []byte) string {
+	return m.encipherMessage(groupLetters(base26Encode(data)))
+}
+
+// decipherBinary deciphers text made by encipherBinary and removes the armor. Carriage returns
+// and tabs are dropped first, since the machine would step on them, so text that has had its
+// line ends or spacing changed in transit still deciphers.
+func (m *Machine) decipherBinary(text string) ([]byte, error) {
+	clean := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || c == ' ' || c == '\n':
+			clean = append(clean, c)
+		case c == '\r' || c == '\t':
+		default:
+			return nil, fmt.Errorf("character %q at offset %d is not a letter", c, i)
+		}
+	}
+	return base26Decode(m.decipherMessage(string(clean)))
+}
+
+func runArmor(args []string) error {
+	fs := flag.NewFlagSet("armor", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	decode := fs.Bool("decode", false, "decipher armored text back to the original file")
+	input := fs.String("i", "", "file to read (default: stdin)")
+	output := fs.String("o", "", "file to write (default: stdout)")
+	fs.Parse(args)
+
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if *input == "" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*input)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *decode {
+		if data, err = m.decipherBinary(string(data)); err != nil {
+			return err
+		}
+	} else {
+		data = []byte(m.encipherBinary(data))
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(*output, data, 0644)
+}
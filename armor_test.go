@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBase26(t *testing.T) {
+	var tests = []struct {
+		data    []byte
+		letters string
+	}{
+		{nil, ""},
+		{[]byte{0}, "AA"},
+		{[]byte{255}, "JV"},
+		{[]byte{0, 0, 0, 1}, "AAAAAAB"},
+		{[]byte{255, 255, 255, 255}, "NXMRLXV"},
+		{[]byte{0, 0, 0, 0, 255, 255}, "AAAAAAADSYP"},
+	}
+	for _, test := range tests {
+		if got := base26Encode(test.data); got != test.letters {
+			t.Errorf("base26Encode(%v) = %q, want %q", test.data, got, test.letters)
+		}
+		if got, err := base26Decode(test.letters); err != nil || !bytes.Equal(got, test.data) {
+			t.Errorf("base26Decode(%q) = %v, %v, want %v", test.letters, got, err, test.data)
+		}
+	}
+
+	// Every length of a chunk, and every byte value, round trip.
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 30; n++ {
+		data := make([]byte, n)
+		rng.Read(data)
+		if got, _ := base26Decode(base26Encode(data)); !bytes.Equal(got, data) {
+			t.Errorf("%d bytes did not round trip: %v became %v", n, data, got)
+		}
+	}
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	if got, _ := base26Decode(strings.ToLower(groupLetters(base26Encode(all)))); !bytes.Equal(got, all) {
+		t.Errorf("all byte values did not round trip")
+	}
+
+	for _, bad := range []string{"AAA", "ZZ", "ZZZZZZZ", "AA-A"} {
+		if _, err := base26Decode(bad); err == nil {
+			t.Errorf("base26Decode(%q) should raise error", bad)
+		}
+	}
+}
+
+func TestGroupLetters(t *testing.T) {
+	if got := groupLetters(""); got != "" {
+		t.Errorf("groupLetters(\"\") = %q", got)
+	}
+	if got := groupLetters("ABCDEFGHIJKL"); got != "ABCDE FGHIJ KL\n" {
+		t.Errorf("groupLetters gave %q", got)
+	}
+	lines := strings.Split(groupLetters(strings.Repeat("A", 120)), "\n")
+	if len(lines) != 4 || len(lines[0]) != 59 || lines[2] != "AAAAA AAAAA AAAAA AAAAA" || lines[3] != "" {
+		t.Errorf("groupLetters gave lines %q", lines)
+	}
+}
+
+func TestEncipherBinary(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(2)).Read(data)
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	cipher := m.encipherBinary(data)
+	if strings.Trim(cipher, "ABCDEFGHIJKLMNOPQRSTUVWXYZ \n") != "" {
+		t.Errorf("enciphered binary has characters other than letters, spaces and newlines")
+	}
+	if base26Encode(data) == strings.Replace(strings.Replace(cipher, " ", "", -1), "\n", "", -1) {
+		t.Errorf("encipherBinary did not encipher")
+	}
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	if got, err := m.decipherBinary(cipher); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decipherBinary failed to recover the data: %v", err)
+	}
+
+	// Line ends changed to CRLF, and spaces to tabs, in transit.
+	mangled := strings.Replace(strings.Replace(cipher, "\n", "\r\n", -1), " ", "\t", -1)
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	if got, err := m.decipherBinary(mangled); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decipherBinary failed to recover the data from CRLF text: %v", err)
+	}
+	if _, err := m.decipherBinary("ABCDE.FG"); err == nil {
+		t.Errorf("decipherBinary of text with punctuation should raise error")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// keySource supplies keys, of the form '9-1,24,6-23', one at a time. The bool result is false
+// when there are no more keys.
+type keySource interface {
+	nextKey() (string, bool)
+}
+
+// keyList is a keySource over a fixed slice of keys.
+type keyList struct {
+	keys []string
+	next int
+}
+
+func (l *keyList) nextKey() (string, bool) {
+	if l.next >= len(l.keys) {
+		return "", false
+	}
+	l.next++
+	return l.keys[l.next-1], true
+}
+
+// BatchResult is the outcome of deciphering with one key in a batch.
+type BatchResult struct {
+	Index     int // Order of the key in the source
+	Key       string
+	Plaintext string  // Empty if Err is not nil
+	Score     float64 // scoreEnglish of the plaintext
+	Err       error   // Error from an invalid key
+}
+
+// batchDecipher deciphers cipher with every key from keys and the given alphabet, spreading the
+// work over workers goroutines (0 means one per CPU). Each worker reuses one Machine and one
+// output buffer for all its keys, and looks letters up in the machineTable cached for the
+// alphabet. Results are sent on the returned channel as they are ready, not necessarily in key
+// order; it is closed once every key is done. Closing done abandons the batch: no more keys are
+// read and no more results sent, so a caller that stops reading early must close it to let the
+// goroutines exit. An invalid alphabet is reported at once as an error.
+func batchDecipher(cipher, alphabet string, keys keySource, workers int, done <-chan struct{}) (<-chan BatchResult, error) {
+	proto, err := NewMachine(1, 1, 1, 1, 1, 2, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	table := cachedMachineTable(proto)
+
+	type job struct {
+		index int
+		key   string
+	}
+	jobs := make(chan job, workers)
+	results := make(chan BatchResult, workers)
+	go func() {
+		defer close(jobs)
+		for i := 0; ; i++ {
+			key, ok := keys.nextKey()
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- job{i, key}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	src := []byte(cipher)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			m := proto.clone()
+			dst := make([]byte, len(src))
+			for j := range jobs {
+				r := BatchResult{Index: j.index, Key: j.key}
+				if r.Err = m.setKey(j.key); r.Err == nil {
+					table.DecipherBytes(m, dst, src)
+					r.Plaintext = string(dst)
+					r.Score = scoreEnglish(r.Plaintext)
+				}
+				select {
+				case results <- r:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
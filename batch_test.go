@@ -0,0 +1,74 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBatchDecipher(t *testing.T) {
+	m, err := NewMachineFromKey(defaultKey, defaultAlphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	cipher := m.encipherMessage(englishSample)
+
+	keys := []string{"1-1,1,1-13", "9-1,24,6-23", "9-1,24,6-11", "25-25,25,25-31", "5-20,7,18-21", defaultKey}
+	results, err := batchDecipher(cipher, defaultAlphabet, &keyList{keys: keys}, 3, nil)
+	if err != nil {
+		t.Fatalf("batchDecipher failed: %s", err)
+	}
+	seen := make(map[int]bool)
+	for r := range results {
+		if seen[r.Index] {
+			t.Errorf("batchDecipher returned key %d twice", r.Index)
+		}
+		seen[r.Index] = true
+		if r.Key != keys[r.Index] {
+			t.Errorf("batchDecipher result %d has key %s, want %s", r.Index, r.Key, keys[r.Index])
+		}
+		switch r.Key {
+		case defaultKey:
+			if r.Plaintext != englishSample || r.Score < 0.2 {
+				t.Errorf("batchDecipher with the right key gave score %.3f, plaintext %s", r.Score, r.Plaintext)
+			}
+		case "9-1,24,6-11":
+			if r.Err == nil {
+				t.Errorf("batchDecipher with invalid key %s should give an error", r.Key)
+			}
+		default:
+			if r.Err != nil || r.Score > 0 {
+				t.Errorf("batchDecipher with the wrong key %s gave score %.3f, error %v", r.Key, r.Score, r.Err)
+			}
+		}
+	}
+	if len(seen) != len(keys) {
+		t.Errorf("batchDecipher returned %d results, want %d", len(seen), len(keys))
+	}
+
+	if _, err := batchDecipher(cipher, "ABC", &keyList{keys: keys}, 0, nil); err == nil {
+		t.Errorf("batchDecipher with a bad alphabet should raise error")
+	}
+}
+
+func TestBatchDecipherDone(t *testing.T) {
+	before := runtime.NumGoroutine()
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = defaultKey
+	}
+	done := make(chan struct{})
+	results, err := batchDecipher("ZTXODNWKCCMAVNZXYWEE", defaultAlphabet, &keyList{keys: keys}, 4, done)
+	if err != nil {
+		t.Fatalf("batchDecipher failed: %s", err)
+	}
+	<-results
+	close(done)
+
+	// Every goroutine should exit, though the results were not all read.
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines still running after done was closed, want %d", runtime.NumGoroutine(), before)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// ITA2 (international Baudot-Murray) shift and control codes. Bit 0 of a code is channel 1 of
+// the tape.
+const (
+	ita2Null    = 0x00
+	ita2LF      = 0x02
+	ita2Space   = 0x04
+	ita2CR      = 0x08
+	ita2Figures = 0x1B
+	ita2Letters = 0x1F
+)
+
+// ita2LetterChars and ita2FigureChars give the character for each code in letters and figures shift.
+// Zero marks codes with no printing character: shifts, controls and national-use positions.
+var (
+	ita2LetterChars = [32]byte{
+		0, 'E', '\n', 'A', ' ', 'S', 'I', 'U', '\r', 'D', 'R', 'J', 'N', 'F', 'C', 'K',
+		'T', 'Z', 'L', 'W', 'H', 'Y', 'P', 'Q', 'O', 'B', 'G', 0, 'M', 'X', 'V', 0,
+	}
+	ita2FigureChars = [32]byte{
+		0, '3', '\n', '-', ' ', '\'', '8', '7', '\r', 0, '4', 0, ',', 0, ':', '(',
+		'5', '+', ')', '2', 0, '6', '0', '1', '9', '?', 0, 0, '.', '/', '=', 0,
+	}
+)
+
+// ita2Encode converts text to ITA2 codes, starting in letters shift and inserting shift codes
+// as needed. Lower-case letters are sent as capitals. It returns an error for characters ITA2
+// cannot send.
+func ita2Encode(text string) ([]byte, error) {
+	var letterCode, figureCode [256]int
+	for i := range letterCode {
+		letterCode[i], figureCode[i] = -1, -1
+	}
+	for code := 31; code >= 0; code-- {
+		if c := ita2LetterChars[code]; c != 0 {
+			letterCode[c] = code
+		}
+		if c := ita2FigureChars[code]; c != 0 {
+			figureCode[c] = code
+		}
+	}
+
+	codes := make([]byte, 0, len(text))
+	figures := false
+	for i, c := range []byte(text) {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		switch {
+		case letterCode[c] >= 0 && figureCode[c] >= 0:
+			// Space, CR and LF are the same in both shifts.
+			codes = append(codes, byte(letterCode[c]))
+		case letterCode[c] >= 0:
+			if figures {
+				codes = append(codes, ita2Letters)
+				figures = false
+			}
+			codes = append(codes, byte(letterCode[c]))
+		case figureCode[c] >= 0:
+			if !figures {
+				codes = append(codes, ita2Figures)
+				figures = true
+			}
+			codes = append(codes, byte(figureCode[c]))
+		default:
+			return nil, fmt.Errorf("character %q at offset %d cannot be sent in ITA2", c, i)
+		}
+	}
+	return codes, nil
+}
+
+// ita2Decode converts ITA2 codes back to text, starting in letters shift. Nulls and codes with
+// no printing character in the current shift are dropped.
+func ita2Decode(codes []byte) (string, error) {
+	var b bytes.Buffer
+	figures := false
+	for i, code := range codes {
+		if code > 31 {
+			return "", fmt.Errorf("code %d at offset %d is not a 5-bit ITA2 code", code, i)
+		}
+		switch code {
+		case ita2Letters:
+			figures = false
+		case ita2Figures:
+			figures = true
+		default:
+			c := ita2LetterChars[code]
+			if figures {
+				c = ita2FigureChars[code]
+			}
+			if c != 0 {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// writeTape draws codes as 5-hole punched tape, one row per code: 'o' for a hole, ' ' for none,
+// and '.' for the feed hole between channels 2 and 3. Each row is followed by what it prints.
+func writeTape(w io.Writer, codes []byte) error {
+	bw := bufio.NewWriter(w)
+	figures := false
+	for _, code := range codes {
+		row := []byte("|  .   |")
+		for ch := 0; ch < 5; ch++ {
+			if code&(1<<uint(ch)) != 0 {
+				col := 1 + ch
+				if ch >= 2 {
+					col++
+				}
+				row[col] = 'o'
+			}
+		}
+		var label string
+		switch code {
+		case ita2Letters:
+			label, figures = "LTRS", false
+		case ita2Figures:
+			label, figures = "FIGS", true
+		case ita2Space:
+			label = "SP"
+		case ita2CR:
+			label = "CR"
+		case ita2LF:
+			label = "LF"
+		case ita2Null:
+			label = "NUL"
+		default:
+			c := ita2LetterChars[code]
+			if figures {
+				c = ita2FigureChars[code]
+			}
+			if c != 0 {
+				label = string(c)
+			}
+		}
+		fmt.Fprintf(bw, "%s %s\n", row, label)
+	}
+	return bw.Flush()
+}
+
+// readTape reads tape drawn by writeTape back into codes, ignoring the labels.
+func readTape(r io.Reader) ([]byte, error) {
+	var codes []byte
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line) < 8 || line[0] != '|' || line[7] != '|' || line[3] != '.' {
+			return nil, fmt.Errorf("tape line %d is not of the form |oo.ooo|", lineno)
+		}
+		var code byte
+		for ch, col := range []int{1, 2, 4, 5, 6} {
+			switch line[col] {
+			case 'o':
+				code |= 1 << uint(ch)
+			case ' ':
+			default:
+				return nil, fmt.Errorf("tape line %d has %q for a hole", lineno, line[col])
+			}
+		}
+		codes = append(codes, code)
+	}
+	return codes, scanner.Err()
+}
+
+func runBaudot(args []string) error {
+	fs := flag.NewFlagSet("baudot", flag.ExitOnError)
+	decode := fs.Bool("decode", false, "decode codes from stdin to text (default: encode text)")
+	format := fs.String("format", "tape", "code format: tape (text drawing) or binary (one code per byte)")
+	fs.Parse(args)
+	if *format != "tape" && *format != "binary" {
+		return fmt.Errorf("format %q should be tape or binary", *format)
+	}
+
+	if *decode {
+		var codes []byte
+		var err error
+		if *format == "tape" {
+			codes, err = readTape(os.Stdin)
+		} else {
+			codes, err = ioutil.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			return err
+		}
+		text, err := ita2Decode(codes)
+		if err != nil {
+			return err
+		}
+		fmt.Print(text)
+		return nil
+	}
+
+	text, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+	codes, err := ita2Encode(text)
+	if err != nil {
+		return err
+	}
+	if *format == "tape" {
+		return writeTape(os.Stdout, codes)
+	}
+	_, err = os.Stdout.Write(codes)
+	return err
+}
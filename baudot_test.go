@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestITA2(t *testing.T) {
+	codes, err := ita2Encode("Ab 12, C")
+	if err != nil {
+		t.Fatalf("ita2Encode failed: %s", err)
+	}
+	want := []byte{0x03, 0x19, ita2Space, ita2Figures, 0x17, 0x13, 0x0C, ita2Space, ita2Letters, 0x0E}
+	if !bytes.Equal(codes, want) {
+		t.Errorf("ita2Encode gave %v, want %v", codes, want)
+	}
+	if text, err := ita2Decode(codes); err != nil || text != "AB 12, C" {
+		t.Errorf("ita2Decode gave %q, %v, want \"AB 12, C\"", text, err)
+	}
+	if _, err := ita2Encode("A@B"); err == nil {
+		t.Errorf("ita2Encode with unsendable character should raise error")
+	}
+	if _, err := ita2Decode([]byte{3, 32}); err == nil {
+		t.Errorf("ita2Decode with code > 31 should raise error")
+	}
+
+	// Every letter and figure survives a round trip.
+	all := "ABCDEFGHIJKLMNOPQRSTUVWXYZ 0123456789-'():+,./?=\r\n"
+	codes, _ = ita2Encode(all)
+	if text, _ := ita2Decode(codes); text != all {
+		t.Errorf("ITA2 round trip gave %q, want %q", text, all)
+	}
+
+	// Simulate transmission of the ciphertext over a teleprinter circuit, via punched tape.
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	cipher := m.encipherMessage(englishSample[:100])
+	codes, _ = ita2Encode(cipher)
+	var tape bytes.Buffer
+	if err := writeTape(&tape, codes); err != nil {
+		t.Fatalf("writeTape failed: %s", err)
+	}
+	received, err := readTape(&tape)
+	if err != nil {
+		t.Fatalf("readTape failed: %s", err)
+	}
+	text, _ := ita2Decode(received)
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	if plain := m.decipherMessage(text); plain != englishSample[:100] {
+		t.Errorf("deciphered transmission gave %s", plain)
+	}
+}
+
+func TestTape(t *testing.T) {
+	var buf bytes.Buffer
+	writeTape(&buf, []byte{0x03, ita2Figures, 0x1D, 0x1F})
+	want := "|oo.   | A\n|oo. oo| FIGS\n|o .ooo| /\n|oo.ooo| LTRS\n"
+	if buf.String() != want {
+		t.Errorf("writeTape gave\n%s\nwant\n%s", buf.String(), want)
+	}
+	if _, err := readTape(strings.NewReader("|ox.   |\n")); err == nil {
+		t.Errorf("readTape with bad hole should raise error")
+	}
+	if _, err := readTape(strings.NewReader("oo ooo\n")); err == nil {
+		t.Errorf("readTape with bad line should raise error")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// channelRates gives the chance, for each letter sent, of each kind of transmission error.
+// Other characters are always received as sent.
+type channelRates struct {
+	Drop       float64 `json:"drop"`       // The letter is lost
+	Insert     float64 `json:"insert"`     // A spurious letter is received before it
+	Substitute float64 `json:"substitute"` // A different letter is received
+	Garble     float64 `json:"garble"`     // The letter is received as the '-' garble marker
+}
+
+func (r channelRates) check() error {
+	for _, rate := range []float64{r.Drop, r.Insert, r.Substitute, r.Garble} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("error rate %g is not between 0 and 1", rate)
+		}
+	}
+	if sum := r.Drop + r.Insert + r.Substitute + r.Garble; sum > 1 {
+		return fmt.Errorf("error rates add up to %g, more than 1", sum)
+	}
+	return nil
+}
+
+// channelError is one error made by the channel, as ground truth for measuring decryption.
+type channelError struct {
+	Kind     string `json:"kind"`     // "drop", "insert", "substitute" or "garble"
+	Sent     int    `json:"sent"`     // Index into the sent text of the letter affected
+	Received int    `json:"received"` // Index into the received text of the error, or where a dropped letter would be
+	Was      string `json:"was"`      // Letter sent, or "" for an insertion
+	Now      string `json:"now"`      // Letter received, or "" for a drop
+}
+
+// transmit passes text through the channel, drawing errors from rng. It returns the text
+// received and every error made, in order. Spurious and substituted letters keep the case
+// of the letter sent.
+func (r channelRates) transmit(text string, rng *rand.Rand) (string, []channelError) {
+	var errs []channelError
+	out := make([]byte, 0, len(text))
+	randomLetter := func(c byte, not byte) byte {
+		base := byte('A')
+		if c >= 'a' && c <= 'z' {
+			base = 'a'
+		}
+		for {
+			if l := base + byte(rng.Intn(26)); l != not {
+				return l
+			}
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+			out = append(out, c)
+			continue
+		}
+		u := rng.Float64()
+		switch {
+		case u < r.Drop:
+			errs = append(errs, channelError{"drop", i, len(out), string(c), ""})
+		case u < r.Drop+r.Insert:
+			l := randomLetter(c, 0)
+			errs = append(errs, channelError{"insert", i, len(out), "", string(l)})
+			out = append(out, l, c)
+		case u < r.Drop+r.Insert+r.Substitute:
+			l := randomLetter(c, c)
+			errs = append(errs, channelError{"substitute", i, len(out), string(c), string(l)})
+			out = append(out, l)
+		case u < r.Drop+r.Insert+r.Substitute+r.Garble:
+			errs = append(errs, channelError{"garble", i, len(out), string(c), "-"})
+			out = append(out, '-')
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out), errs
+}
+
+// letterAccuracy returns the fraction of the letters of want that got has, case-insensitively,
+// at the same index.
+func letterAccuracy(got, want string) float64 {
+	letters, right := 0, 0
+	for i := 0; i < len(want); i++ {
+		c := want[i]
+		if !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+			continue
+		}
+		letters++
+		if i < len(got) && strings.EqualFold(got[i:i+1], want[i:i+1]) {
+			right++
+		}
+	}
+	if letters == 0 {
+		return 1
+	}
+	return float64(right) / float64(letters)
+}
+
+// channelTrial is one message sent through a noisy channel, with the ground truth and how
+// well it was deciphered.
+type channelTrial struct {
+	Rates          channelRates   `json:"rates"`
+	Seed           int64          `json:"seed"`
+	Plain          string         `json:"plain"`
+	Sent           string         `json:"sent"`
+	Received       string         `json:"received"`
+	Errors         []channelError `json:"errors"`
+	Deciphered     string         `json:"deciphered"`  // By decipherMessage
+	Resynced       string         `json:"resynced"`    // By resyncDecipher
+	Corrections    []Correction   `json:"corrections"` // Made by resyncDecipher
+	Accuracy       float64        `json:"accuracy"`    // Letters of plain recovered by decipherMessage
+	ResyncAccuracy float64        `json:"resync_accuracy"`
+}
+
+// runChannelTrial enciphers plain on a copy of m, sends it through a channel with the given rates
+// and seed, and deciphers what is received both directly and with resynchronisation.
+func runChannelTrial(m *Machine, plain string, rates channelRates, seed int64) channelTrial {
+	t := channelTrial{Rates: rates, Seed: seed, Plain: plain}
+	t.Sent = m.clone().encipherMessage(plain)
+	t.Received, t.Errors = rates.transmit(t.Sent, rand.New(rand.NewSource(seed)))
+	t.Deciphered = m.clone().decipherMessage(t.Received)
+	r := m.clone().resyncDecipher(t.Received, resyncWindow, resyncMaxShift)
+	t.Resynced, t.Corrections = r.Plaintext, r.Corrections
+	t.Accuracy = letterAccuracy(t.Deciphered, plain)
+	t.ResyncAccuracy = letterAccuracy(t.Resynced, plain)
+	return t
+}
+
+func runChannel(args []string) error {
+	fs := flag.NewFlagSet("channel", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	var rates channelRates
+	fs.Float64Var(&rates.Drop, "drop", 0, "chance of each letter being lost")
+	fs.Float64Var(&rates.Insert, "insert", 0, "chance of a spurious letter before each letter")
+	fs.Float64Var(&rates.Substitute, "substitute", 0, "chance of each letter being received as another")
+	fs.Float64Var(&rates.Garble, "garble", 0, "chance of each letter being received as a '-' garble")
+	seed := fs.Int64("seed", 1, "seed for the channel errors")
+	trials := fs.Int("trials", 1, "number of trials, with seeds counting up from -seed")
+	asJSON := fs.Bool("json", false, "write each trial, with ground truth, as a line of JSON")
+	fs.Parse(args)
+
+	if err := rates.check(); err != nil {
+		return err
+	}
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	plain, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	var total, totalResync float64
+	for i := 0; i < *trials; i++ {
+		t := runChannelTrial(m, plain, rates, *seed+int64(i))
+		total += t.Accuracy
+		totalResync += t.ResyncAccuracy
+		if *asJSON {
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+			continue
+		}
+		if *trials > 1 {
+			fmt.Printf("seed %d: %d errors, %.1f%% recovered directly, %.1f%% with resync\n",
+				t.Seed, len(t.Errors), 100*t.Accuracy, 100*t.ResyncAccuracy)
+			continue
+		}
+		fmt.Printf("sent       %s\nreceived   %s\n", t.Sent, t.Received)
+		for _, e := range t.Errors {
+			fmt.Printf("  %-10s sent[%d] %q -> received[%d] %q\n", e.Kind, e.Sent, e.Was, e.Received, e.Now)
+		}
+		fmt.Printf("deciphered %s (%.1f%% of letters)\n", t.Deciphered, 100*t.Accuracy)
+		fmt.Printf("resynced   %s (%.1f%% of letters)\n", t.Resynced, 100*t.ResyncAccuracy)
+		for _, c := range t.Corrections {
+			fmt.Printf("  correction at %d: shift %d\n", c.Offset, c.Shift)
+		}
+	}
+	if *trials > 1 && !*asJSON {
+		fmt.Printf("mean: %.1f%% recovered directly, %.1f%% with resync\n",
+			100*total/float64(*trials), 100*totalResync/float64(*trials))
+	}
+	return nil
+}
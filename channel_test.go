@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTransmit(t *testing.T) {
+	text := "ABCDE FGHIJ\nklmno"
+	if got, errs := (channelRates{}).transmit(text, rand.New(rand.NewSource(1))); got != text || len(errs) != 0 {
+		t.Errorf("error-free channel received %q with errors %v", got, errs)
+	}
+
+	// Every kind of error, and the ground truth lets the sent text be rebuilt.
+	rates := channelRates{Drop: 0.1, Insert: 0.1, Substitute: 0.1, Garble: 0.1}
+	text = strings.Repeat("THEQUICKBROWNFOX jumps\n", 20)
+	got, errs := rates.transmit(text, rand.New(rand.NewSource(7)))
+	kinds := make(map[string]int)
+	rebuilt := []byte(got)
+	for i := len(errs) - 1; i >= 0; i-- {
+		e := errs[i]
+		kinds[e.Kind]++
+		switch e.Kind {
+		case "drop":
+			rebuilt = append(rebuilt[:e.Received], append([]byte(e.Was), rebuilt[e.Received:]...)...)
+		case "insert":
+			if string(rebuilt[e.Received]) != e.Now {
+				t.Errorf("insertion %v does not match received text", e)
+			}
+			rebuilt = append(rebuilt[:e.Received], rebuilt[e.Received+1:]...)
+		default:
+			if string(rebuilt[e.Received]) != e.Now {
+				t.Errorf("%s %v does not match received text", e.Kind, e)
+			}
+			if e.Was == e.Now {
+				t.Errorf("%s %v left the letter unchanged", e.Kind, e)
+			}
+			rebuilt[e.Received] = e.Was[0]
+		}
+		if e.Was != "" && text[e.Sent:e.Sent+1] != e.Was {
+			t.Errorf("%s %v does not match sent text", e.Kind, e)
+		}
+	}
+	if string(rebuilt) != text {
+		t.Errorf("ground truth rebuilt\n%s\nwant\n%s", rebuilt, text)
+	}
+	for _, kind := range []string{"drop", "insert", "substitute", "garble"} {
+		if kinds[kind] < 20 || kinds[kind] > 75 {
+			t.Errorf("%d %s errors in %d letters at rate 0.1", kinds[kind], kind, 400)
+		}
+	}
+
+	// The same seed gives the same errors.
+	again, _ := rates.transmit(text, rand.New(rand.NewSource(7)))
+	if again != got {
+		t.Errorf("transmit with the same seed gave different text")
+	}
+
+	for _, bad := range []channelRates{{Drop: -0.1}, {Garble: 1.5}, {Drop: 0.6, Insert: 0.6}} {
+		if bad.check() == nil {
+			t.Errorf("rates %+v should raise error", bad)
+		}
+	}
+}
+
+func TestChannelTrial(t *testing.T) {
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	start := m.state()
+
+	// Garbles and substitutions cost only the letters hit.
+	trial := runChannelTrial(m, englishSample, channelRates{Substitute: 0.02, Garble: 0.05}, 3)
+	if trial.Accuracy != trial.ResyncAccuracy || len(trial.Corrections) != 0 {
+		t.Errorf("resync made corrections %v without drops or insertions", trial.Corrections)
+	}
+	if want := 1 - float64(len(trial.Errors))/float64(len(englishSample)); trial.Accuracy != want {
+		t.Errorf("accuracy %.3f with %d errors, want %.3f", trial.Accuracy, len(trial.Errors), want)
+	}
+
+	// A single drop ruins the rest of the message, unless it is resynchronised.
+	trial = runChannelTrial(m, englishSample, channelRates{Drop: 0.005}, 2)
+	if len(trial.Errors) == 0 {
+		t.Fatalf("no letters dropped; choose another seed")
+	}
+	if trial.Accuracy > 0.9 || trial.ResyncAccuracy < 0.9 {
+		t.Errorf("after %d drops, %.3f recovered directly and %.3f with resync",
+			len(trial.Errors), trial.Accuracy, trial.ResyncAccuracy)
+	}
+
+	// The machine given is not changed.
+	if m.state() != start {
+		t.Errorf("runChannelTrial changed the machine state to %v", m.state())
+	}
+}
+
+func TestLetterAccuracy(t *testing.T) {
+	var tests = []struct {
+		got, want string
+		acc       float64
+	}{
+		{"ABCD", "abcd", 1},
+		{"AB-D", "ABCD", 0.75},
+		{"AB", "ABCD", 0.5},
+		{"A B", "A-B", 1},
+		{"", "", 1},
+	}
+	for _, test := range tests {
+		if acc := letterAccuracy(test.got, test.want); acc != test.acc {
+			t.Errorf("letterAccuracy(%q, %q) = %g, want %g", test.got, test.want, acc, test.acc)
+		}
+	}
+}
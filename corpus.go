@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// corpusMessage is one solved message: its settings and its ciphertext and plaintext, line by line.
+//
+// In a corpus file, lines starting with '#' are comments, header lines of the form "name: value"
+// give the key, alphabet and (optionally) garble marker, and the text follows as pairs of lines
+// "C <ciphertext>" and "P <plaintext>" of equal length. Blank lines are ignored. The garble
+// marker (default '-') stands for a letter that was lost in either text.
+type corpusMessage struct {
+	Name     string
+	Key      string
+	Alphabet string
+	Garble   byte
+	Cipher   []string
+	Plain    []string
+}
+
+// readCorpusMessage reads one message in corpus format from r. The name is used in errors.
+func readCorpusMessage(name string, r io.Reader) (*corpusMessage, error) {
+	msg := &corpusMessage{Name: name, Garble: '-'}
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "C "):
+			msg.Cipher = append(msg.Cipher, strings.TrimSpace(line[2:]))
+		case strings.HasPrefix(line, "P "):
+			if len(msg.Plain) >= len(msg.Cipher) {
+				return nil, fmt.Errorf("%s:%d: plaintext line without ciphertext line", name, lineno)
+			}
+			p := strings.TrimSpace(line[2:])
+			if c := msg.Cipher[len(msg.Plain)]; len(c) != len(p) {
+				return nil, fmt.Errorf("%s:%d: plaintext has %d characters, ciphertext %d", name, lineno, len(p), len(c))
+			}
+			msg.Plain = append(msg.Plain, p)
+		default:
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("%s:%d: cannot parse %q", name, lineno, line)
+			}
+			value := strings.TrimSpace(parts[1])
+			switch strings.TrimSpace(parts[0]) {
+			case "key":
+				msg.Key = value
+			case "alphabet":
+				msg.Alphabet = value
+			case "garble":
+				if len(value) != 1 {
+					return nil, fmt.Errorf("%s:%d: garble marker %q must be one character", name, lineno, value)
+				}
+				msg.Garble = value[0]
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown header %q", name, lineno, parts[0])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if msg.Key == "" || msg.Alphabet == "" {
+		return nil, fmt.Errorf("%s: key and alphabet are both required", name)
+	}
+	if len(msg.Cipher) == 0 || len(msg.Plain) != len(msg.Cipher) {
+		return nil, fmt.Errorf("%s: %d ciphertext and %d plaintext lines, need equal and nonzero",
+			name, len(msg.Cipher), len(msg.Plain))
+	}
+	return msg, nil
+}
+
+// loadCorpus reads every *.msg file in dir, in name order. The corpus in testdata/corpus holds
+// only part 1 of the 14-part message for now; parts 2-14 go in as further files once their
+// texts are transcribed.
+func loadCorpus(dir string) ([]*corpusMessage, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.msg"))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []*corpusMessage
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := readCorpusMessage(filepath.Base(name), f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// check deciphers the message from its key and compares it with the plaintext, ignoring
+// columns garbled in either the ciphertext or the plaintext.
+func (msg *corpusMessage) check() (textDiff, error) {
+	m, err := NewMachineFromKey(msg.Key, msg.Alphabet)
+	if err != nil {
+		return textDiff{}, fmt.Errorf("%s: %s", msg.Name, err)
+	}
+	return compareText(m, strings.Join(msg.Cipher, "\n"), strings.Join(msg.Plain, "\n"), msg.Garble), nil
+}
+
+func runCorpus(args []string) error {
+	fs := flag.NewFlagSet("corpus", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Join("testdata", "corpus"), "directory of *.msg corpus files")
+	fs.Parse(args)
+
+	msgs, err := loadCorpus(*dir)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, msg := range msgs {
+		d, err := msg.check()
+		if err != nil {
+			return err
+		}
+		if d.Diffs == 0 {
+			fmt.Printf("%s: ok (%d lines)\n", msg.Name, len(msg.Plain))
+			continue
+		}
+		failed++
+		fmt.Printf("%s:\n", msg.Name)
+		d.write(os.Stdout, false)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages did not decipher correctly", failed, len(msgs))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCorpus deciphers every message in testdata/corpus and compares it with its plaintext. The
+// corpus holds only part 1 of the 14-part message for now, so it checks the harness more than it
+// adds coverage; new parts need only a new file.
+func TestCorpus(t *testing.T) {
+	msgs, err := loadCorpus(filepath.Join("testdata", "corpus"))
+	if err != nil {
+		t.Fatalf("loadCorpus failed: %s", err)
+	}
+	if len(msgs) == 0 {
+		t.Fatalf("loadCorpus found no messages")
+	}
+	for _, msg := range msgs {
+		d, err := msg.check()
+		if err != nil {
+			t.Errorf("%s: %s", msg.Name, err)
+		} else if d.Diffs > 0 {
+			t.Errorf("%s deciphered incorrectly:\n%s", msg.Name, d)
+		}
+	}
+}
+
+func TestCorpusMessage(t *testing.T) {
+	text := `# test
+key: 9-1,24,6-23
+alphabet: NOKTYUXEQLHBRMPDICJASVWGZF
+garble: *
+
+C ZTXODNWKCC
+P FOVTATAKID
+C MAV*ZXYW
+P ASINXMU*
+`
+	msg, err := readCorpusMessage("test", strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("readCorpusMessage failed: %s", err)
+	}
+	if msg.Garble != '*' || len(msg.Cipher) != 2 {
+		t.Errorf("readCorpusMessage gave %+v", msg)
+	}
+	d, err := msg.check()
+	if err != nil {
+		t.Fatalf("check failed: %s", err)
+	}
+	if d.Diffs != 1 || d.FirstLine != 1 || d.FirstColumn != 4 {
+		t.Errorf("check found differences\n%s\nwant line 2 column 5 only", d)
+	}
+
+	for _, bad := range []string{
+		"key: 1-1,1,1-12\nC AB\nP AB\n",
+		"alphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\nC AB\nP AB\n",
+		"key: 1-1,1,1-12\nalphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\nC ABC\nP AB\n",
+		"key: 1-1,1,1-12\nalphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\nP AB\n",
+		"key: 1-1,1,1-12\nalphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\nC AB\n",
+		"key: 1-1,1,1-12\nalphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\ncolor: purple\nC AB\nP AB\n",
+		"key: 1-1,1,1-12\nalphabet: ABCDEFGHIJKLMNOPQRSTUVWXYZ\ngarble: --\nC AB\nP AB\n",
+	} {
+		if _, err := readCorpusMessage("bad", strings.NewReader(bad)); err == nil {
+			t.Errorf("readCorpusMessage(%q) should raise error", bad)
+		}
+	}
+}
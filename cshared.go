@@ -0,0 +1,127 @@
+//go:build cshared
+// +build cshared
+
+// This file exports the emulator as a C shared library, for use from C and from Python with
+// ctypes. Build it with
+//
+//	go build -tags cshared -buildmode=c-shared -o libpurple.so
+//
+// which also writes the C declarations to libpurple.h. See python/purple.py for an example.
+
+package main
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Machines are handed to C as integer handles, since C may not keep Go pointers.
+var (
+	cMachinesMu sync.Mutex
+	cMachines   = make(map[C.uintptr_t]*Machine)
+	cNextHandle C.uintptr_t
+)
+
+// cMachine returns the machine for handle h, or nil if there is none.
+func cMachine(h C.uintptr_t) *Machine {
+	cMachinesMu.Lock()
+	defer cMachinesMu.Unlock()
+	return cMachines[h]
+}
+
+// cBytes returns the n bytes of C memory at p as a slice, without copying.
+func cBytes(p *C.char, n C.int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	return (*[1 << 30]byte)(unsafe.Pointer(p))[:n:n]
+}
+
+// purple_new makes a machine from a key such as "9-1,24,6-23" and a 26-letter plugboard
+// alphabet, and returns its handle. On failure it returns 0 and, if err is not NULL, writes
+// a NUL-terminated message of at most errlen bytes to err.
+//
+//export purple_new
+func purple_new(key, alphabet *C.char, err *C.char, errlen C.int) C.uintptr_t {
+	m, e := NewMachineFromKey(C.GoString(key), C.GoString(alphabet))
+	if e != nil {
+		if err != nil && errlen > 0 {
+			msg := cBytes(err, errlen)
+			msg[copy(msg[:len(msg)-1], e.Error())] = 0
+		}
+		return 0
+	}
+	cMachinesMu.Lock()
+	defer cMachinesMu.Unlock()
+	cNextHandle++
+	cMachines[cNextHandle] = m
+	return cNextHandle
+}
+
+// purple_encipher enciphers n bytes from src into dst, which may be the same buffer, as
+// EncipherBytes does. It returns 0, or -1 if h is not a machine.
+//
+//export purple_encipher
+func purple_encipher(h C.uintptr_t, src, dst *C.char, n C.int) C.int {
+	m := cMachine(h)
+	if m == nil {
+		return -1
+	}
+	m.EncipherBytes(cBytes(dst, n), cBytes(src, n))
+	return 0
+}
+
+// purple_decipher deciphers n bytes from src into dst, as purple_encipher enciphers.
+//
+//export purple_decipher
+func purple_decipher(h C.uintptr_t, src, dst *C.char, n C.int) C.int {
+	m := cMachine(h)
+	if m == nil {
+		return -1
+	}
+	m.DecipherBytes(cBytes(dst, n), cBytes(src, n))
+	return 0
+}
+
+// purple_step steps the machine n times without a letter. It returns 0, or -1 if h is not
+// a machine.
+//
+//export purple_step
+func purple_step(h C.uintptr_t, n C.int) C.int {
+	m := cMachine(h)
+	if m == nil {
+		return -1
+	}
+	for i := C.int(0); i < n; i++ {
+		m.step()
+	}
+	return 0
+}
+
+// purple_state writes the positions (1-25, as in keys) of the sixes, fast, middle and slow
+// switches. It returns 0, or -1 if h is not a machine.
+//
+//export purple_state
+func purple_state(h C.uintptr_t, sixes, fast, middle, slow *C.int) C.int {
+	m := cMachine(h)
+	if m == nil {
+		return -1
+	}
+	st := m.state()
+	*sixes, *fast, *middle, *slow = C.int(st.Sixes+1), C.int(st.Fast+1), C.int(st.Middle+1), C.int(st.Slow+1)
+	return 0
+}
+
+// purple_free releases the machine. The handle must not be used again.
+//
+//export purple_free
+func purple_free(h C.uintptr_t) {
+	cMachinesMu.Lock()
+	defer cMachinesMu.Unlock()
+	delete(cMachines, h)
+}
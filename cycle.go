@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Which twenties switch moved in one step of the machine.
+const (
+	steppedFast = iota
+	steppedMiddle
+	steppedSlow
+)
+
+var steppedNames = []string{"fast", "middle", "slow"}
+
+// roleStateIndex numbers the tableStates possible switch states by the positions of the sixes,
+// fast, middle and slow switches. This is not the numbering of machineTable, which goes by
+// twenties #1, #2 and #3 and so depends on which switch is fast; the two agree only for 1-2-3.
+func roleStateIndex(s State) int {
+	return ((s.Sixes*25+s.Fast)*25+s.Middle)*25 + s.Slow
+}
+
+// stepWhich steps m and returns which twenties switch moved.
+func (m *Machine) stepWhich() int {
+	before := m.state()
+	m.step()
+	after := m.state()
+	switch {
+	case after.Middle != before.Middle:
+		return steppedMiddle
+	case after.Slow != before.Slow:
+		return steppedSlow
+	}
+	return steppedFast
+}
+
+// cycleReport describes the motion of the switches from one starting state.
+type cycleReport struct {
+	Start       State
+	Tail        int    // Steps taken before the first state that later recurs
+	Period      int    // Steps from that state until it recurs
+	Recurring   State  // The first state that recurs
+	Steps       [3]int // Steps of the fast, middle and slow switches in one period
+	MiddleSteps []int  // Step numbers within the first period at which the middle switch moved
+	SlowSteps   []int  // Step numbers within the first period at which the slow switch moved
+}
+
+// analyzeCycle follows Machine.step from start until some state repeats. The step rule is
+// invertible, so in practice Tail is always 0 and the start itself recurs.
+func analyzeCycle(start State) cycleReport {
+	m, _ := NewMachine(1, 1, 1, 1, 1, 2, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	m.setState(start)
+	r := cycleReport{Start: start}
+	seen := make([]int, tableStates) // Step number at which each state was first seen, plus 1
+	var which []int
+	for n := 0; ; n++ {
+		st := m.state()
+		idx := roleStateIndex(st)
+		if seen[idx] > 0 {
+			r.Tail = seen[idx] - 1
+			r.Period = n - r.Tail
+			r.Recurring = st
+			break
+		}
+		seen[idx] = n + 1
+		which = append(which, m.stepWhich())
+	}
+	for n, w := range which[r.Tail:] {
+		r.Steps[w]++
+		if w == steppedMiddle {
+			r.MiddleSteps = append(r.MiddleSteps, r.Tail+n)
+		} else if w == steppedSlow {
+			r.SlowSteps = append(r.SlowSteps, r.Tail+n)
+		}
+	}
+	return r
+}
+
+// gaps summarises the intervals between successive step numbers as interval -> count.
+func gaps(steps []int) map[int]int {
+	g := make(map[int]int)
+	for i := 1; i < len(steps); i++ {
+		g[steps[i]-steps[i-1]]++
+	}
+	return g
+}
+
+// writeReport prints r in readable form. Positions are 1-25, as in keys.
+func (r cycleReport) writeReport(w io.Writer) {
+	pos := func(s State) string {
+		return fmt.Sprintf("sixes %d, fast %d, middle %d, slow %d", s.Sixes+1, s.Fast+1, s.Middle+1, s.Slow+1)
+	}
+	fmt.Fprintf(w, "Start:     %s\n", pos(r.Start))
+	fmt.Fprintf(w, "Recurring: %s, first at step %d\n", pos(r.Recurring), r.Tail)
+	fmt.Fprintf(w, "Period:    %d steps\n", r.Period)
+	for i, name := range steppedNames {
+		fmt.Fprintf(w, "%-7s switch steps %d times per period\n", name, r.Steps[i])
+	}
+	for _, s := range []struct {
+		name  string
+		steps []int
+	}{{"middle", r.MiddleSteps}, {"slow", r.SlowSteps}} {
+		if len(s.steps) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s switch first steps at step %d; intervals between its steps:", s.name, s.steps[0])
+		g := gaps(s.steps)
+		var keys []int
+		for k := range g {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, " %d (x%d)", k, g[k])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeMotion writes n steps of the motion of the switches from start as CSV: the step number,
+// the positions (1-25) before the step, and which twenties switch then moved.
+func writeMotion(w io.Writer, start State, n int) error {
+	m, _ := NewMachine(1, 1, 1, 1, 1, 2, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	m.setState(start)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"step", "sixes", "fast", "middle", "slow", "stepped"})
+	for i := 0; i < n; i++ {
+		s := m.state()
+		cw.Write([]string{
+			strconv.Itoa(i),
+			strconv.Itoa(s.Sixes + 1),
+			strconv.Itoa(s.Fast + 1),
+			strconv.Itoa(s.Middle + 1),
+			strconv.Itoa(s.Slow + 1),
+			steppedNames[m.stepWhich()],
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func runCycle(args []string) error {
+	fs := flag.NewFlagSet("cycle", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	csvFile := fs.String("csv", "", "also write the motion of the switches as CSV to this file")
+	steps := fs.Int("steps", 0, "number of steps to write as CSV (default one full period)")
+	fs.Parse(args)
+
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	r := analyzeCycle(m.state())
+	r.writeReport(os.Stdout)
+	if *csvFile == "" {
+		return nil
+	}
+	n := *steps
+	if n <= 0 {
+		n = r.Tail + r.Period
+	}
+	f, err := os.Create(*csvFile)
+	if err != nil {
+		return err
+	}
+	if err := writeMotion(f, r.Start, n); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCycle(t *testing.T) {
+	for _, start := range []State{{0, 0, 0, 0}, {8, 23, 5, 0}, {23, 3, 24, 7}} {
+		r := analyzeCycle(start)
+		if r.Tail != 0 || r.Recurring != start {
+			t.Errorf("analyzeCycle(%v) found recurring state %v after %d steps, want the start after 0",
+				start, r.Recurring, r.Tail)
+		}
+		if r.Period != 15625 {
+			t.Errorf("analyzeCycle(%v) period = %d, want 15625", start, r.Period)
+		}
+		if r.Steps != [3]int{14975, 625, 25} {
+			t.Errorf("analyzeCycle(%v) steps = %v, want [14975 625 25]", start, r.Steps)
+		}
+		if g := gaps(r.MiddleSteps); len(g) != 1 || g[25] != 624 {
+			t.Errorf("analyzeCycle(%v) middle switch intervals %v, want 624 of 25", start, g)
+		}
+		if g := gaps(r.SlowSteps); len(g) != 1 || g[625] != 24 {
+			t.Errorf("analyzeCycle(%v) slow switch intervals %v, want 24 of 625", start, g)
+		}
+	}
+
+	// Compare with TestSwitchMotion
+	var buf bytes.Buffer
+	if err := writeMotion(&buf, State{20, 0, 24, 4}, 7); err != nil {
+		t.Fatalf("writeMotion failed: %s", err)
+	}
+	want := `step,sixes,fast,middle,slow,stepped
+0,21,1,25,5,fast
+1,22,2,25,5,fast
+2,23,3,25,5,fast
+3,24,4,25,5,slow
+4,25,4,25,6,middle
+5,1,4,1,6,fast
+6,2,5,1,6,fast
+`
+	if buf.String() != want {
+		t.Errorf("writeMotion wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	var report bytes.Buffer
+	analyzeCycle(State{}).writeReport(&report)
+	if !strings.Contains(report.String(), "Period:    15625 steps") {
+		t.Errorf("writeReport did not give the period:\n%s", report.String())
+	}
+}
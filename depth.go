@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cipherMessage is one intercepted ciphertext, reduced to its upper-case letters and '-' placeholders.
+type cipherMessage struct {
+	Name string
+	Text string
+}
+
+// cipherLetters returns the letters of s in upper case, dropping spaces and newlines, on which
+// the machine does not step. Any other character, such as a '-' garble or a digit, steps the
+// machine, so it becomes a '-' placeholder that keeps offsets in the text equal to machine
+// offsets; it never counts as a coincidence.
+func cipherLetters(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			b = append(b, c)
+		case c >= 'a' && c <= 'z':
+			b = append(b, c-('a'-'A'))
+		case c != ' ' && c != '\n':
+			b = append(b, '-')
+		}
+	}
+	return string(b)
+}
+
+// loadCiphertexts reads every regular file in dir, in name order, as one message.
+func loadCiphertexts(dir string) ([]cipherMessage, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []cipherMessage
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, cipherMessage{info.Name(), cipherLetters(string(data))})
+	}
+	return msgs, nil
+}
+
+// depthCandidate is an alignment of two messages whose letters coincide more often than chance,
+// suggesting that they were enciphered on the same sequence of machine states.
+type depthCandidate struct {
+	A, B    int     // Indices of the two messages
+	Offset  int     // Letter i of B lines up with letter i+Offset of A
+	Overlap int     // Letters compared
+	Matches int     // Letters that coincide
+	Z       float64 // Standard deviations above the chance rate of coincidence
+}
+
+// coincidences counts the letters that coincide when b[i] is aligned with a[i+offset]. Places
+// where either message has a garble are not compared.
+func coincidences(a, b string, offset int) (overlap, matches int) {
+	for i := 0; i < len(b); i++ {
+		j := i + offset
+		if j < 0 {
+			continue
+		}
+		if j >= len(a) {
+			break
+		}
+		if a[j] == '-' || b[i] == '-' {
+			continue
+		}
+		overlap++
+		if a[j] == b[i] {
+			matches++
+		}
+	}
+	return
+}
+
+// findDepths compares every pair of messages at every offset up to maxOffset in each direction
+// and returns the alignments with at least minOverlap letters and a coincidence z-score of at
+// least minZ, best first. The chance rate of coincidence comes from the letter frequencies of
+// the two messages, since PURPLE ciphertext is not uniform: the sixes carry more than 6/26 of it.
+func findDepths(msgs []cipherMessage, maxOffset, minOverlap int, minZ float64) []depthCandidate {
+	freqs := make([][26]float64, len(msgs))
+	for i, msg := range msgs {
+		n := len(msg.Text) - strings.Count(msg.Text, "-")
+		for _, c := range []byte(msg.Text) {
+			if c != '-' {
+				freqs[i][c-'A'] += 1 / float64(n)
+			}
+		}
+	}
+	var found []depthCandidate
+	for a := range msgs {
+		for b := a + 1; b < len(msgs); b++ {
+			// Chance of a coincidence between unrelated letters of the two messages
+			p := 0.0
+			for c := range freqs[a] {
+				p += freqs[a][c] * freqs[b][c]
+			}
+			if p <= 0 || p >= 1 {
+				// No letters in common, or one letter only: coincidences tell nothing, and the
+				// z-score would be 0/0.
+				continue
+			}
+			for offset := -maxOffset; offset <= maxOffset; offset++ {
+				overlap, matches := coincidences(msgs[a].Text, msgs[b].Text, offset)
+				if overlap < minOverlap {
+					continue
+				}
+				n := float64(overlap)
+				z := (float64(matches) - n*p) / math.Sqrt(n*p*(1-p))
+				if z >= minZ {
+					found = append(found, depthCandidate{a, b, offset, overlap, matches, z})
+				}
+			}
+		}
+	}
+	// found is in order of A, B and Offset, which a stable sort keeps for equal scores.
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Z > found[j].Z })
+	return found
+}
+
+// patternOf returns the repetition pattern of s: each letter is replaced by the letter for the
+// order of its first appearance, so "PURPLE" and "ABCAXY" both give "ABCADE". It also returns
+// how many letters of s repeat an earlier letter.
+func patternOf(s string) (string, int) {
+	var first [26]byte
+	next := byte('A')
+	repeats := 0
+	b := make([]byte, len(s))
+	for i, c := range []byte(s) {
+		if first[c-'A'] == 0 {
+			first[c-'A'] = next
+			next++
+		} else {
+			repeats++
+		}
+		b[i] = first[c-'A']
+	}
+	return string(b), repeats
+}
+
+// occurrence locates a stretch of ciphertext.
+type occurrence struct {
+	Msg    int // Index of the message
+	Offset int // Letter offset within the message
+	Text   string
+}
+
+// isomorph is a repetition pattern found at several places in the traffic.
+type isomorph struct {
+	Pattern     string
+	Occurrences []occurrence
+}
+
+// findIsomorphs finds every stretch of length letters, without garbles, whose pattern has at
+// least minRepeats repeated letters and that occurs more than once in msgs. Stretches with identical text at
+// the same pattern are included, since they may be plain repeats in depth. The result is
+// ordered by number of occurrences, then by pattern.
+func findIsomorphs(msgs []cipherMessage, length, minRepeats int) []isomorph {
+	byPattern := make(map[string][]occurrence)
+	for m, msg := range msgs {
+		for i := 0; i+length <= len(msg.Text); i++ {
+			text := msg.Text[i : i+length]
+			if strings.IndexByte(text, '-') >= 0 {
+				continue
+			}
+			pattern, repeats := patternOf(text)
+			if repeats >= minRepeats {
+				byPattern[pattern] = append(byPattern[pattern], occurrence{m, i, text})
+			}
+		}
+	}
+	var found []isomorph
+	for pattern, occ := range byPattern {
+		if len(occ) > 1 {
+			found = append(found, isomorph{pattern, occ})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if len(found[i].Occurrences) != len(found[j].Occurrences) {
+			return len(found[i].Occurrences) > len(found[j].Occurrences)
+		}
+		return found[i].Pattern < found[j].Pattern
+	})
+	return found
+}
+
+func runDepth(args []string) error {
+	fs := flag.NewFlagSet("depth", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory of ciphertext files, one message per file")
+	maxOffset := fs.Int("max-offset", 500, "largest alignment offset tried between two messages")
+	minOverlap := fs.Int("min-overlap", 40, "fewest letters in common for an alignment to count")
+	minZ := fs.Float64("z", 4, "smallest coincidence z-score reported as depth")
+	isoLength := fs.Int("iso-length", 10, "length of isomorphic stretches to look for")
+	isoRepeats := fs.Int("iso-repeats", 3, "fewest repeated letters in an isomorphic pattern")
+	fs.Parse(args)
+
+	msgs, err := loadCiphertexts(*dir)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		return fmt.Errorf("no ciphertexts in %s", *dir)
+	}
+
+	fmt.Printf("Depth candidates (%d messages):\n", len(msgs))
+	for _, d := range findDepths(msgs, *maxOffset, *minOverlap, *minZ) {
+		fmt.Printf("  %s + %d = %s  overlap %d  matches %d (%.1f%%)  z %.1f\n",
+			msgs[d.B].Name, d.Offset, msgs[d.A].Name, d.Overlap, d.Matches,
+			100*float64(d.Matches)/float64(d.Overlap), d.Z)
+	}
+	fmt.Printf("\nIsomorphs of length %d with at least %d repeats:\n", *isoLength, *isoRepeats)
+	for _, iso := range findIsomorphs(msgs, *isoLength, *isoRepeats) {
+		var where []string
+		for _, o := range iso.Occurrences {
+			where = append(where, fmt.Sprintf("%s@%d:%s", msgs[o.Msg].Name, o.Offset, o.Text))
+		}
+		fmt.Printf("  %s  %s\n", iso.Pattern, strings.Join(where, " "))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reverse returns s backwards, which keeps English letter frequencies but changes the text.
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func TestFindDepths(t *testing.T) {
+	m, err := NewMachineFromKey(defaultKey, defaultAlphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	a := m.encipherMessage(strings.Repeat(englishSample, 5))
+	// Two letters of a are garbled in the overlap, and one before it.
+	a = cipherLetters(a[:10] + "-" + a[11:50] + "-" + a[51:60] + "-" + a[61:])
+
+	// b is a different text on the same key, started 40 letters later.
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	for i := 0; i < 40; i++ {
+		m.step()
+	}
+	b := m.encipherMessage(reverse(strings.Repeat(englishSample, 4)))
+
+	// c is unrelated text on an unrelated key.
+	m, _ = NewMachineFromKey("3-7,11,19-32", defaultAlphabet)
+	random := make([]byte, 500)
+	for i, x := 0, 1; i < len(random); i++ {
+		x = (x*1103515245 + 12345) % (1 << 31)
+		random[i] = 'A' + byte(x>>16)%26
+	}
+	c := m.encipherMessage(string(random))
+
+	msgs := []cipherMessage{{"a", a}, {"b", b}, {"c", c}}
+	found := findDepths(msgs, 100, 40, 5)
+	if len(found) == 0 {
+		t.Fatalf("findDepths found no depth")
+	}
+	d := found[0]
+	if d.A != 0 || d.B != 1 || d.Offset != 40 || d.Overlap != len(b)-2 {
+		t.Errorf("findDepths best candidate is %+v, want messages 0, 1 at offset 40", d)
+	}
+	for _, d := range found {
+		if d.A == 2 || d.B == 2 {
+			t.Errorf("findDepths found depth %+v with the unrelated message", d)
+		}
+	}
+
+	// Messages with no letters in common, or of one letter only, give no z-score.
+	same := []cipherMessage{{"x", strings.Repeat("A", 60)}, {"y", strings.Repeat("A", 60)}, {"z", strings.Repeat("B", 60)}}
+	if found := findDepths(same, 10, 40, 5); len(found) != 0 {
+		t.Errorf("findDepths on single-letter messages found %+v", found)
+	}
+}
+
+func TestIsomorphs(t *testing.T) {
+	if p, r := patternOf("PURPLE"); p != "ABCADE" || r != 1 {
+		t.Errorf("patternOf(PURPLE) = %s, %d, want ABCADE, 1", p, r)
+	}
+	msgs := []cipherMessage{
+		{"x", "ZZABCADBQ"},
+		{"y", "KLMNOMPNR"},
+		{"z", "ZZABC-DBQ"},
+	}
+	found := findIsomorphs(msgs, 6, 2)
+	if len(found) != 1 || found[0].Pattern != "ABCADB" {
+		t.Fatalf("findIsomorphs found %+v, want one of pattern ABCADB", found)
+	}
+	occ := found[0].Occurrences
+	if len(occ) != 2 || occ[0] != (occurrence{0, 2, "ABCADB"}) || occ[1] != (occurrence{1, 2, "MNOMPN"}) {
+		t.Errorf("findIsomorphs found occurrences %+v", occ)
+	}
+}
+
+func TestLoadCiphertexts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "purple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "2.txt"), []byte("xyz 1-2\nAB"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "1.txt"), []byte("QRS"), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+
+	msgs, err := loadCiphertexts(dir)
+	if err != nil {
+		t.Fatalf("loadCiphertexts failed: %s", err)
+	}
+	if len(msgs) != 2 || msgs[0] != (cipherMessage{"1.txt", "QRS"}) || msgs[1] != (cipherMessage{"2.txt", "XYZ---AB"}) {
+		t.Errorf("loadCiphertexts gave %+v", msgs)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// lineDiff compares one line of deciphered text with the expected plaintext.
+type lineDiff struct {
+	Line   int // Line number, from 0
+	Offset int // Machine steps from the key to the start of the line
+	Cipher string
+	Got    string // Deciphered text
+	Want   string // Expected plaintext
+	Marks  string // Per column: ' ' agrees, '^' differs, '.' garbled in cipher or plaintext
+	Diffs  int    // Number of '^' in Marks
+}
+
+// textDiff is the result of compareText.
+type textDiff struct {
+	Lines       []lineDiff // Every line, including those that agree
+	Diffs       int        // Differing columns in all lines
+	FirstLine   int        // Line of the first difference, or -1 if there is none
+	FirstColumn int        // Column of the first difference, from 0
+	FirstOffset int        // Machine steps from the key to the first difference
+	FirstState  State      // Switch positions used for the first differing letter
+}
+
+// compareText deciphers cipher on a copy of m (leaving m unchanged) and compares it, line by
+// line, with want. Columns holding the garble marker in either text are not compared; a line
+// longer in one text than the other differs in each extra column.
+func compareText(m *Machine, cipher, want string, garble byte) textDiff {
+	w := m.clone()
+	d := textDiff{FirstLine: -1}
+	cipherLines := strings.Split(cipher, "\n")
+	wantLines := strings.Split(want, "\n")
+	for len(wantLines) < len(cipherLines) {
+		wantLines = append(wantLines, "")
+	}
+	offset := 0
+	for i, want := range wantLines {
+		var c string
+		if i < len(cipherLines) {
+			c = cipherLines[i]
+		}
+		ld := lineDiff{Line: i, Offset: offset, Cipher: c, Want: want}
+		n := len(c)
+		if len(want) > n {
+			n = len(want)
+		}
+		got := make([]byte, 0, len(c))
+		marks := make([]byte, n)
+		for j := 0; j < n; j++ {
+			st := w.state()
+			if j < len(c) {
+				got = append(got, w.decipherMessage(c[j:j+1])...)
+			}
+			switch {
+			case j < len(c) && j < len(want) && (c[j] == garble || want[j] == garble):
+				marks[j] = '.'
+			case j < len(c) && j < len(want) && got[j] == want[j]:
+				marks[j] = ' '
+			default:
+				marks[j] = '^'
+				ld.Diffs++
+				if d.FirstLine < 0 {
+					d.FirstLine, d.FirstColumn, d.FirstOffset, d.FirstState = i, j, offset, st
+				}
+			}
+			if j < len(c) && c[j] != ' ' {
+				offset++
+			}
+		}
+		ld.Got = string(got)
+		ld.Marks = strings.TrimRight(string(marks), " ")
+		d.Diffs += ld.Diffs
+		d.Lines = append(d.Lines, ld)
+	}
+	return d
+}
+
+// write prints the lines of d that differ, with their machine offsets, and the switch
+// positions (1-25) at the first difference. With all set, agreeing lines are printed too.
+func (d textDiff) write(w io.Writer, all bool) {
+	for _, ld := range d.Lines {
+		if ld.Diffs == 0 && !all {
+			continue
+		}
+		fmt.Fprintf(w, "line %d (machine offset %d): %d differences\n", ld.Line+1, ld.Offset, ld.Diffs)
+		fmt.Fprintf(w, "  cipher %s\n  got    %s\n  want   %s\n", ld.Cipher, ld.Got, ld.Want)
+		if ld.Marks != "" {
+			fmt.Fprintf(w, "         %s\n", ld.Marks)
+		}
+	}
+	if d.FirstLine < 0 {
+		fmt.Fprintf(w, "no differences\n")
+		return
+	}
+	s := d.FirstState
+	fmt.Fprintf(w, "%d differences; first at line %d column %d, machine offset %d: sixes %d fast %d middle %d slow %d\n",
+		d.Diffs, d.FirstLine+1, d.FirstColumn+1, d.FirstOffset, s.Sixes+1, s.Fast+1, s.Middle+1, s.Slow+1)
+}
+
+// String returns the report of write, for use in test failures.
+func (d textDiff) String() string {
+	var buf bytes.Buffer
+	d.write(&buf, false)
+	return buf.String()
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	cipherFile := fs.String("cipher", "", "file of ciphertext (required)")
+	plainFile := fs.String("plain", "", "file of expected plaintext, aligned line by line (required)")
+	garble := fs.String("garble", "-", "garble marker")
+	all := fs.Bool("all", false, "show lines that agree too")
+	fs.Parse(args)
+
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	if *cipherFile == "" || *plainFile == "" {
+		return fmt.Errorf("both -cipher and -plain are required")
+	}
+	if len(*garble) != 1 {
+		return fmt.Errorf("garble marker %q must be one character", *garble)
+	}
+	cipher, err := ioutil.ReadFile(*cipherFile)
+	if err != nil {
+		return err
+	}
+	plain, err := ioutil.ReadFile(*plainFile)
+	if err != nil {
+		return err
+	}
+	d := compareText(m, strings.TrimRight(string(cipher), "\n"), strings.TrimRight(string(plain), "\n"), (*garble)[0])
+	d.write(os.Stdout, *all)
+	if d.Diffs > 0 {
+		return fmt.Errorf("deciphered text differs from %s", *plainFile)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareText(t *testing.T) {
+	m, err := NewMachineFromKey(defaultKey, defaultAlphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	cipher := "ZTXODNWKCC\nMAV-ZXYW\nEE"
+	want := "FOVTATAKID\nASINXMUI\nMIN"
+
+	d := compareText(m, cipher, want, '-')
+	if st := m.state(); st != (State{8, 23, 5, 0}) {
+		t.Errorf("compareText changed the machine state to %v", st)
+	}
+	if len(d.Lines) != 3 || d.Lines[0].Diffs != 0 || d.Lines[1].Diffs != 1 || d.Lines[2].Diffs != 1 {
+		t.Fatalf("compareText gave lines %+v", d.Lines)
+	}
+	if d.Diffs != 2 || d.FirstLine != 1 || d.FirstColumn != 4 || d.FirstOffset != 14 {
+		t.Errorf("compareText first difference at line %d column %d offset %d (%d total), want 1, 4, 14 (2)",
+			d.FirstLine, d.FirstColumn, d.FirstOffset, d.Diffs)
+	}
+	// The switches at offset 14, from the TestTrace table pattern: sixes and fast step each time.
+	if d.FirstState != (State{22, 12, 5, 0}) {
+		t.Errorf("compareText first difference in state %v, want {22 12 5 0}", d.FirstState)
+	}
+	if l := d.Lines[1]; l.Offset != 10 || l.Got != "ASI-IMUI" || l.Marks != "   .^" {
+		t.Errorf("compareText line 1 = %+v", l)
+	}
+	if l := d.Lines[2]; l.Marks != "  ^" {
+		t.Errorf("compareText line 2 marks %q, want \"  ^\" for the missing letter", l.Marks)
+	}
+
+	report := d.String()
+	for _, s := range []string{"line 2 (machine offset 10): 1 differences", "  want   ASINXMUI\n            .^",
+		"first at line 2 column 5, machine offset 14: sixes 23 fast 13 middle 6 slow 1"} {
+		if !strings.Contains(report, s) {
+			t.Errorf("textDiff report does not contain %q:\n%s", s, report)
+		}
+	}
+	if strings.Contains(report, "line 1 ") {
+		t.Errorf("textDiff report shows a line that agrees:\n%s", report)
+	}
+	if d := compareText(m, cipher[:10], want[:10], '-'); d.Diffs != 0 || !strings.Contains(d.String(), "no differences") {
+		t.Errorf("compareText of correct text gave %s", d)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// cryptoSource is a math/rand Source that draws from crypto/rand. Seed has no effect.
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	var b [8]byte
+	if _, err := io.ReadFull(crand.Reader, b[:]); err != nil {
+		panic("purple: crypto/rand failed: " + err.Error())
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]) >> 1)
+}
+
+func (cryptoSource) Seed(int64) {}
+
+// keyGenerator makes random switch settings and plugboard alphabets.
+type keyGenerator struct {
+	rng *rand.Rand
+}
+
+// newKeyGenerator returns a generator drawing from crypto/rand.
+func newKeyGenerator() *keyGenerator {
+	return &keyGenerator{rand.New(cryptoSource{})}
+}
+
+// newSeededKeyGenerator returns a generator whose output is fixed by seed, for reproducibility.
+func newSeededKeyGenerator(seed int64) *keyGenerator {
+	return &keyGenerator{rand.New(rand.NewSource(seed))}
+}
+
+// key returns random switch settings of the form '9-1,24,6-23', acceptable to NewMachineFromKey.
+func (g *keyGenerator) key() string {
+	fast := 1 + g.rng.Intn(3)
+	middle := 1 + (fast+g.rng.Intn(2))%3
+	return fmt.Sprintf("%d-%d,%d,%d-%d%d", 1+g.rng.Intn(25), 1+g.rng.Intn(25), 1+g.rng.Intn(25),
+		1+g.rng.Intn(25), fast, middle)
+}
+
+// alphabet returns a random plugboard alphabet, a permutation of A-Z.
+func (g *keyGenerator) alphabet() string {
+	b := make([]byte, 26)
+	for i, p := range g.rng.Perm(26) {
+		b[i] = 'A' + byte(p)
+	}
+	return string(b)
+}
+
+// writeKeyList writes one line "date key alphabet" for each day from first to last inclusive.
+func (g *keyGenerator) writeKeyList(w io.Writer, first, last time.Time) error {
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", d.Format("2006-01-02"), g.key(), g.alphabet()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	n := fs.Int("n", 1, "number of keys to generate, without -from")
+	seed := fs.Int64("seed", 0, "seed for reproducible keys (default: crypto/rand)")
+	from := fs.String("from", "", "first date (YYYY-MM-DD) of a daily key list")
+	to := fs.String("to", "", "last date of a daily key list (default: same as -from)")
+	fs.Parse(args)
+
+	g := newKeyGenerator()
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			g = newSeededKeyGenerator(*seed)
+		}
+	})
+
+	if *from == "" {
+		for i := 0; i < *n; i++ {
+			fmt.Printf("%s %s\n", g.key(), g.alphabet())
+		}
+		return nil
+	}
+	first, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		return err
+	}
+	last := first
+	if *to != "" {
+		if last, err = time.Parse("2006-01-02", *to); err != nil {
+			return err
+		}
+	}
+	if last.Before(first) {
+		return fmt.Errorf("-to %s is before -from %s", *to, *from)
+	}
+	return g.writeKeyList(os.Stdout, first, last)
+}
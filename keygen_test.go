@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyGenerator(t *testing.T) {
+	for _, g := range []*keyGenerator{newKeyGenerator(), newSeededKeyGenerator(1941)} {
+		orders := make(map[string]bool)
+		for i := 0; i < 500; i++ {
+			key, alphabet := g.key(), g.alphabet()
+			if _, err := NewMachineFromKey(key, alphabet); err != nil {
+				t.Fatalf("generated key %s, alphabet %s are invalid: %s", key, alphabet, err)
+			}
+			orders[key[strings.LastIndex(key, "-")+1:]] = true
+		}
+		if len(orders) != 6 {
+			t.Errorf("generated keys used %d fast/middle orders, want all 6: %v", len(orders), orders)
+		}
+	}
+
+	// Seeded generators are reproducible.
+	g1, g2 := newSeededKeyGenerator(7), newSeededKeyGenerator(7)
+	for i := 0; i < 10; i++ {
+		if k1, k2 := g1.key()+g1.alphabet(), g2.key()+g2.alphabet(); k1 != k2 {
+			t.Errorf("seeded generators differ: %s, %s", k1, k2)
+		}
+	}
+
+	var buf bytes.Buffer
+	first := time.Date(1941, 11, 30, 0, 0, 0, 0, time.UTC)
+	last := time.Date(1941, 12, 7, 0, 0, 0, 0, time.UTC)
+	if err := newSeededKeyGenerator(1).writeKeyList(&buf, first, last); err != nil {
+		t.Fatalf("writeKeyList failed: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], "1941-11-30 ") || !strings.HasPrefix(lines[7], "1941-12-07 ") {
+		t.Errorf("writeKeyList gave\n%s", buf.String())
+	}
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			t.Errorf("writeKeyList line %q should have 3 fields", line)
+		} else if _, err := NewMachineFromKey(f[1], f[2]); err != nil {
+			t.Errorf("writeKeyList line %q is invalid: %s", line, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// fastMiddleOrders lists the six legal (fast, middle) choices of twenties switches.
+var fastMiddleOrders = [6][2]int{{1, 2}, {1, 3}, {2, 1}, {2, 3}, {3, 1}, {3, 2}}
+
+// keySpaceSize is the number of distinct keys accepted by NewMachine.
+const keySpaceSize = len(fastMiddleOrders) * 25 * 25 * 25 * 25
+
+// keyAt returns key number i (0 <= i < keySpaceSize) in enumeration order: the fast/middle order
+// varies slowest, then the sixes and twenties switches #1, #2 and #3.
+func keyAt(i int) string {
+	tw3 := i % 25
+	i /= 25
+	tw2 := i % 25
+	i /= 25
+	tw1 := i % 25
+	i /= 25
+	six := i % 25
+	order := fastMiddleOrders[i/25]
+	return fmt.Sprintf("%d-%d,%d,%d-%d%d", six+1, tw1+1, tw2+1, tw3+1, order[0], order[1])
+}
+
+// keyIndex returns the enumeration number of key, the inverse of keyAt.
+func keyIndex(key string) (int, error) {
+	sixpos, tw1pos, tw2pos, tw3pos, fast, middle, err := parseKey(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle); err != nil {
+		return 0, err
+	}
+	order := 0
+	for fastMiddleOrders[order] != [2]int{fast, middle} {
+		order++
+	}
+	return (((order*25+sixpos-1)*25+tw1pos-1)*25+tw2pos-1)*25 + tw3pos - 1, nil
+}
+
+// keyIterator walks a contiguous range of the key space. It is a keySource.
+type keyIterator struct {
+	start, next, end int // Enumeration numbers of the first and next keys, and of the end of the range
+}
+
+// newKeyIterator returns an iterator over shard number shard (0 <= shard < nshards) of the key
+// space. The shards are contiguous, nearly equal and together cover every key exactly once.
+func newKeyIterator(shard, nshards int) (*keyIterator, error) {
+	if nshards < 1 || shard < 0 || shard >= nshards {
+		return nil, fmt.Errorf("shard %d of %d: need 0 <= shard < shards", shard, nshards)
+	}
+	start := shard * keySpaceSize / nshards
+	return &keyIterator{start, start, (shard + 1) * keySpaceSize / nshards}, nil
+}
+
+// resumeAfter moves the iterator to the key following key, which must lie in its range, so
+// that an interrupted walk can carry on from the last key it tried.
+func (it *keyIterator) resumeAfter(key string) error {
+	i, err := keyIndex(key)
+	if err != nil {
+		return err
+	}
+	if i < it.start || i >= it.end {
+		return fmt.Errorf("key %s is not in this shard", key)
+	}
+	it.next = i + 1
+	return nil
+}
+
+// remaining returns the number of keys left.
+func (it *keyIterator) remaining() int {
+	return it.end - it.next
+}
+
+func (it *keyIterator) nextKey() (string, bool) {
+	if it.next >= it.end {
+		return "", false
+	}
+	it.next++
+	return keyAt(it.next - 1), true
+}
+
+func runKeys(args []string) error {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	shard := fs.Int("shard", 0, "which shard of the key space to list, from 0")
+	shards := fs.Int("shards", 1, "number of shards the key space is split into")
+	resume := fs.String("resume", "", "start after this key, within the shard")
+	count := fs.Int("count", 0, "stop after this many keys (default: the whole shard)")
+	fs.Parse(args)
+
+	it, err := newKeyIterator(*shard, *shards)
+	if err != nil {
+		return err
+	}
+	if *resume != "" {
+		if err := it.resumeAfter(*resume); err != nil {
+			return err
+		}
+	}
+	for n := 0; *count <= 0 || n < *count; n++ {
+		key, ok := it.nextKey()
+		if !ok {
+			break
+		}
+		fmt.Println(key)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestKeySpace(t *testing.T) {
+	if keySpaceSize != 2343750 {
+		t.Errorf("keySpaceSize = %d, want 2343750", keySpaceSize)
+	}
+	for _, i := range []int{0, 1, 24, 25, 390624, 390625, 1234567, keySpaceSize - 1} {
+		key := keyAt(i)
+		j, err := keyIndex(key)
+		if err != nil || j != i {
+			t.Errorf("keyIndex(keyAt(%d) = %s) = %d, %v", i, key, j, err)
+		}
+		if _, err := NewMachineFromKey(key, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"); err != nil {
+			t.Errorf("keyAt(%d) = %s is not a valid key: %s", i, key, err)
+		}
+	}
+	if k := keyAt(0); k != "1-1,1,1-12" {
+		t.Errorf("keyAt(0) = %s, want 1-1,1,1-12", k)
+	}
+	if k := keyAt(keySpaceSize - 1); k != "25-25,25,25-32" {
+		t.Errorf("keyAt(last) = %s, want 25-25,25,25-32", k)
+	}
+	for _, bad := range []string{"1-1,1,1-11", "0-1,1,1-12", "bad"} {
+		if _, err := keyIndex(bad); err == nil {
+			t.Errorf("keyIndex(%s) should raise error", bad)
+		}
+	}
+}
+
+func TestKeyIterator(t *testing.T) {
+	// Shards cover the key space exactly once, in order.
+	const nshards = 7
+	next := 0
+	for shard := 0; shard < nshards; shard++ {
+		it, err := newKeyIterator(shard, nshards)
+		if err != nil {
+			t.Fatalf("newKeyIterator(%d, %d) failed: %s", shard, nshards, err)
+		}
+		if it.start != next {
+			t.Errorf("shard %d starts at %d, want %d", shard, it.start, next)
+		}
+		next = it.end
+	}
+	if next != keySpaceSize {
+		t.Errorf("shards end at %d, want %d", next, keySpaceSize)
+	}
+
+	it, _ := newKeyIterator(3, nshards)
+	var keys []string
+	for i := 0; i < 5; i++ {
+		key, ok := it.nextKey()
+		if !ok {
+			t.Fatalf("nextKey ended early")
+		}
+		keys = append(keys, key)
+	}
+	resumed, _ := newKeyIterator(3, nshards)
+	if err := resumed.resumeAfter(keys[2]); err != nil {
+		t.Fatalf("resumeAfter failed: %s", err)
+	}
+	if key, _ := resumed.nextKey(); key != keys[3] {
+		t.Errorf("after resuming at %s, nextKey = %s, want %s", keys[2], key, keys[3])
+	}
+	if resumed.remaining() != it.remaining()+1 {
+		t.Errorf("resumed iterator has %d keys left, want %d", resumed.remaining(), it.remaining()+1)
+	}
+	if err := resumed.resumeAfter(keyAt(0)); err == nil {
+		t.Errorf("resumeAfter with a key in another shard should raise error")
+	}
+
+	last, _ := newKeyIterator(0, 1)
+	last.resumeAfter(keyAt(keySpaceSize - 2))
+	if key, ok := last.nextKey(); !ok || key != keyAt(keySpaceSize-1) {
+		t.Errorf("nextKey = %s, %v, want the last key", key, ok)
+	}
+	if _, ok := last.nextKey(); ok {
+		t.Errorf("nextKey past the end should return false")
+	}
+	for _, bad := range [][2]int{{0, 0}, {-1, 3}, {3, 3}} {
+		if _, err := newKeyIterator(bad[0], bad[1]); err == nil {
+			t.Errorf("newKeyIterator(%d, %d) should raise error", bad[0], bad[1])
+		}
+	}
+}
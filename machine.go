@@ -16,6 +16,7 @@ type Machine struct {
 	alphabet     string
 	plugboardIn  [26]byte
 	plugboardOut [26]byte
+	trace        func(TraceEvent)
 }
 
 // NewMachineFromKey creates a pointer to a new instance of a PURPLE machine, configured according to arguments.
@@ -30,77 +31,62 @@ type Machine struct {
 //
 // Example: '9-1,24,6-23'
 func NewMachineFromKey(key, alphabet string) (*Machine, error) {
+	sixpos, tw1pos, tw2pos, tw3pos, fast, middle, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewMachine(sixpos, tw1pos, tw2pos, tw3pos, fast, middle, alphabet)
+}
+
+// parseKey splits a key of the form '9-1,24,6-23' into the arguments of NewMachine, without
+// checking their ranges.
+func parseKey(key string) (sixpos, tw1pos, tw2pos, tw3pos, fast, middle int, err error) {
 	parts := strings.Split(key, "-")
 	if len(parts) != 3 {
-		return nil, fmt.Errorf("Key was not of the form 9-1,24,6-23")
+		err = fmt.Errorf("Key was not of the form 9-1,24,6-23")
+		return
 	}
 	sixes, twenties, permutation := parts[0], parts[1], parts[2]
 
 	tparts := strings.Split(twenties, ",")
 	if len(tparts) != 3 {
-		return nil, fmt.Errorf("Key was not of the form 9-1,24,6-23")
+		err = fmt.Errorf("Key was not of the form 9-1,24,6-23")
+		return
 	}
 
-	sixpos, err := strconv.Atoi(sixes)
-	if err != nil {
-		return nil, err
+	if sixpos, err = strconv.Atoi(sixes); err != nil {
+		return
 	}
-	tw1pos, err := strconv.Atoi(tparts[0])
-	if err != nil {
-		return nil, err
+	if tw1pos, err = strconv.Atoi(tparts[0]); err != nil {
+		return
 	}
-	tw2pos, err := strconv.Atoi(tparts[1])
-	if err != nil {
-		return nil, err
+	if tw2pos, err = strconv.Atoi(tparts[1]); err != nil {
+		return
 	}
-	tw3pos, err := strconv.Atoi(tparts[2])
-	if err != nil {
-		return nil, err
+	if tw3pos, err = strconv.Atoi(tparts[2]); err != nil {
+		return
 	}
 	permnum, err := strconv.Atoi(permutation)
 	if err != nil {
-		return nil, err
+		return
 	}
-	fast := permnum / 10
-	middle := permnum % 10
-	return NewMachine(sixpos, tw1pos, tw2pos, tw3pos, fast, middle, alphabet)
+	fast = permnum / 10
+	middle = permnum % 10
+	return
 }
 
 // NewMachine creates a pointer to a new instance of a PURPLE machine, configured according to arguments.
+// Each machine has its own copies of the switches, sharing only their wiring, so machines step
+// independently of one another.
 func NewMachine(sixpos, tw1pos, tw2pos, tw3pos, fast, middle int, alphabet string) (*Machine, error) {
-	if sixpos < 1 || sixpos > 25 ||
-		tw1pos < 1 || tw1pos > 25 ||
-		tw2pos < 1 || tw2pos > 25 ||
-		tw3pos < 1 || tw3pos > 25 {
-		return nil, fmt.Errorf("switch positions [%d, %d, %d, %d] should all be in range 1-25",
-			sixpos, tw1pos, tw2pos, tw3pos)
-	}
 	m := new(Machine)
-	m.sixes = sixesSwitch
-	m.sixes.setPosition(sixpos - 1)
-	if fast == middle {
-		return nil, fmt.Errorf("fast and middle (%d, %d) must be different", fast, middle)
-	}
-	if fast < 1 || fast > 3 {
-		return nil, fmt.Errorf("fast = %d, must be in [1,3]", fast)
-	}
-	if middle < 1 || middle > 3 {
-		return nil, fmt.Errorf("middle = %d, must be in [1,3]", middle)
-	}
-	m.twenties[0] = twenties1
-	m.twenties[1] = twenties2
-	m.twenties[2] = twenties3
-	m.fast = m.twenties[fast-1]
-	m.middle = m.twenties[middle-1]
-	for i := 1; i <= 3; i++ {
-		if i != fast && i != middle {
-			m.slow = m.twenties[i-1]
-			break
-		}
+	m.sixes = sixesSwitch.clone()
+	m.twenties[0] = twenties1.clone()
+	m.twenties[1] = twenties2.clone()
+	m.twenties[2] = twenties3.clone()
+	if err := m.setSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle); err != nil {
+		return nil, err
 	}
-	m.twenties[0].setPosition(tw1pos - 1)
-	m.twenties[1].setPosition(tw2pos - 1)
-	m.twenties[2].setPosition(tw3pos - 1)
 
 	// Validate the alphabet
 	if len(alphabet) != 26 {
@@ -128,6 +114,95 @@ func NewMachine(sixpos, tw1pos, tw2pos, tw3pos, fast, middle int, alphabet strin
 	return m, nil
 }
 
+// setKey moves the switches of m to the settings in key, of the form '9-1,24,6-23', keeping the
+// alphabet. It reuses the switches of m rather than making new ones, so one Machine can try
+// many keys.
+func (m *Machine) setKey(key string) error {
+	sixpos, tw1pos, tw2pos, tw3pos, fast, middle, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	return m.setSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle)
+}
+
+// checkSwitches returns an error if the switch settings are not acceptable to NewMachine.
+func checkSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle int) error {
+	if sixpos < 1 || sixpos > 25 ||
+		tw1pos < 1 || tw1pos > 25 ||
+		tw2pos < 1 || tw2pos > 25 ||
+		tw3pos < 1 || tw3pos > 25 {
+		return fmt.Errorf("switch positions [%d, %d, %d, %d] should all be in range 1-25",
+			sixpos, tw1pos, tw2pos, tw3pos)
+	}
+	if fast == middle {
+		return fmt.Errorf("fast and middle (%d, %d) must be different", fast, middle)
+	}
+	if fast < 1 || fast > 3 {
+		return fmt.Errorf("fast = %d, must be in [1,3]", fast)
+	}
+	if middle < 1 || middle > 3 {
+		return fmt.Errorf("middle = %d, must be in [1,3]", middle)
+	}
+	return nil
+}
+
+// setSwitches checks the switch settings (as for NewMachine) and applies them to m.
+func (m *Machine) setSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle int) error {
+	if err := checkSwitches(sixpos, tw1pos, tw2pos, tw3pos, fast, middle); err != nil {
+		return err
+	}
+	m.sixes.setPosition(sixpos - 1)
+	m.fast = m.twenties[fast-1]
+	m.middle = m.twenties[middle-1]
+	for i := 1; i <= 3; i++ {
+		if i != fast && i != middle {
+			m.slow = m.twenties[i-1]
+			break
+		}
+	}
+	m.twenties[0].setPosition(tw1pos - 1)
+	m.twenties[1].setPosition(tw2pos - 1)
+	m.twenties[2].setPosition(tw3pos - 1)
+	return nil
+}
+
+// State holds the positions (0-24) of the sixes, fast, middle and slow switches.
+type State struct {
+	Sixes, Fast, Middle, Slow int
+}
+
+// state returns the current positions of the machine's switches.
+func (m *Machine) state() State {
+	return State{m.sixes.position, m.fast.position, m.middle.position, m.slow.position}
+}
+
+// setState moves the machine's switches to the positions in s.
+func (m *Machine) setState(s State) {
+	m.sixes.setPosition(s.Sixes)
+	m.fast.setPosition(s.Fast)
+	m.middle.setPosition(s.Middle)
+	m.slow.setPosition(s.Slow)
+}
+
+// clone returns an independent copy of m, in the same state. The copy has no trace hook, so
+// trial runs on clones are not reported to m's.
+func (m *Machine) clone() *Machine {
+	c := *m
+	c.trace = nil
+	c.sixes = m.sixes.clone()
+	for i := range m.twenties {
+		c.twenties[i] = m.twenties[i].clone()
+		if m.twenties[i] == m.fast {
+			c.fast = c.twenties[i]
+		} else if m.twenties[i] == m.middle {
+			c.middle = c.twenties[i]
+		} else {
+			c.slow = c.twenties[i]
+		}
+	}
+	return &c
+}
+
 // step advances the sixes switch and exactly one twenties switch, according
 // the the rules of the machine.
 func (m *Machine) step() {
@@ -164,35 +239,59 @@ func (m *Machine) encipher(p byte) (c byte) {
 }
 
 func (m *Machine) decipherMessage(cipher string) string {
-	result := make([]byte, len(cipher))
-	for i, c := range []byte(cipher) {
+	result := []byte(cipher)
+	m.DecipherBytes(result, result)
+	return string(result)
+}
+
+func (m *Machine) encipherMessage(plain string) string {
+	result := []byte(plain)
+	m.EncipherBytes(result, result)
+	return string(result)
+}
+
+// DecipherBytes deciphers src into dst, exactly as decipherMessage does, without allocating.
+// Dst may be src itself, to work in place. It panics if dst is shorter than src.
+func (m *Machine) DecipherBytes(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("purple: DecipherBytes output smaller than input")
+	}
+	for i, c := range src {
 		if c >= 'A' && c <= 'Z' {
-			result[i] = m.decipher(c-'A') + 'A'
+			dst[i] = m.decipher(c-'A') + 'A'
 		} else if c >= 'a' && c <= 'z' {
-			result[i] = m.decipher(c-'a') + 'a'
+			dst[i] = m.decipher(c-'a') + 'a'
 		} else {
-			result[i] = c
+			dst[i] = c
+		}
+		if m.trace != nil {
+			m.traceLetter(i, false, c, dst[i])
 		}
 		if c != ' ' && c != '\n' {
 			m.step()
 		}
 	}
-	return string(result)
 }
 
-func (m *Machine) encipherMessage(plain string) string {
-	result := make([]byte, len(plain))
-	for i, p := range []byte(plain) {
+// EncipherBytes enciphers src into dst, exactly as encipherMessage does, without allocating.
+// Dst may be src itself, to work in place. It panics if dst is shorter than src.
+func (m *Machine) EncipherBytes(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("purple: EncipherBytes output smaller than input")
+	}
+	for i, p := range src {
 		if p >= 'A' && p <= 'Z' {
-			result[i] = m.encipher(p-'A') + 'A'
+			dst[i] = m.encipher(p-'A') + 'A'
 		} else if p >= 'a' && p <= 'z' {
-			result[i] = m.encipher(p-'a') + 'a'
+			dst[i] = m.encipher(p-'a') + 'a'
 		} else {
-			result[i] = p
+			dst[i] = p
+		}
+		if m.trace != nil {
+			m.traceLetter(i, true, p, dst[i])
 		}
 		if p != ' ' && p != '\n' {
 			m.step()
 		}
 	}
-	return string(result)
 }
@@ -109,6 +109,36 @@ func TestSwitchMotion(t *testing.T) {
 	}
 }
 
+func TestIndependentMachines(t *testing.T) {
+	// TestSwitch moves the global switches itself, so compare against where they are now.
+	globalSixes, globalTwenties1 := sixesSwitch.position, twenties1.position
+	key := "9-1,24,6-23"
+	alphabet := "NOKTYUXEQLHBRMPDICJASVWGZF"
+	m1, err := NewMachineFromKey(key, alphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	m2, _ := NewMachineFromKey(key, alphabet)
+	m3, _ := NewMachineFromKey("1-1,1,1-13", alphabet)
+	start := m2.state()
+	for i := 0; i < 30; i++ {
+		m1.step()
+	}
+	if m2.state() != start {
+		t.Errorf("stepping one machine moved another from %v to %v", start, m2.state())
+	}
+	if m3.state() != (State{0, 0, 0, 0}) {
+		t.Errorf("stepping one machine moved a third, with another key, to %v", m3.state())
+	}
+	if m1.state() == start {
+		t.Errorf("machine did not move in 30 steps")
+	}
+	if sixesSwitch.position != globalSixes || twenties1.position != globalTwenties1 {
+		t.Errorf("machines moved the shared switch wiring from %d, %d to %d, %d",
+			globalSixes, globalTwenties1, sixesSwitch.position, twenties1.position)
+	}
+}
+
 func Test14PartMessage(t *testing.T) {
 	// This is part 1 of the famous 14 part message. The cipherlines and plainlines are
 	// laid on top of each other, every other line, with blank lines inserted for
@@ -216,7 +246,8 @@ func Test14PartMessage(t *testing.T) {
 
 	deciphered := machine.decipherMessage(ciphertext)
 	if plaintext != deciphered {
-		t.Errorf("Incorrectly deciphered the 14-part message")
+		start, _ := NewMachineFromKey(key, alphabet)
+		t.Errorf("Incorrectly deciphered the 14-part message\n%s", compareText(start, ciphertext, plaintext, '-'))
 	}
 
 	machine, err = NewMachineFromKey(key, alphabet)
@@ -271,3 +302,74 @@ func TestOneLine(t *testing.T) {
 	fmt.Printf("PlugboardO: %v\n", machine.plugboardOut)
 	// fmt.Printf("%s\n", machine.encipherMessage(plaintext))
 }
+
+func TestCipherBytes(t *testing.T) {
+	key := "9-1,24,6-23"
+	alphabet := "NOKTYUXEQLHBRMPDICJASVWGZF"
+	plain := []byte("FOVTATAKIDASINIMUIMINOMOXI woirubesifyxx-FCKZZR\n")
+	m1, _ := NewMachineFromKey(key, alphabet)
+	m2, _ := NewMachineFromKey(key, alphabet)
+	want := m1.encipherMessage(string(plain))
+
+	cipher := make([]byte, len(plain)+5)
+	m2.EncipherBytes(cipher, plain)
+	if string(cipher[:len(plain)]) != want {
+		t.Errorf("EncipherBytes gives %q, want %q", cipher[:len(plain)], want)
+	}
+
+	// Decipher in place
+	m1, _ = NewMachineFromKey(key, alphabet)
+	buf := []byte(want)
+	m1.DecipherBytes(buf, buf)
+	if string(buf) != string(plain) {
+		t.Errorf("DecipherBytes in place gives %q, want %q", buf, plain)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m1.DecipherBytes(buf, buf)
+		m1.EncipherBytes(cipher, buf)
+	})
+	if allocs != 0 {
+		t.Errorf("DecipherBytes and EncipherBytes made %.1f allocations, want 0", allocs)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("DecipherBytes with short output should panic")
+		}
+	}()
+	m1.DecipherBytes(buf[:3], buf)
+}
+
+func BenchmarkDecipherMessage(b *testing.B) {
+	m, cipher := benchmarkCipher(b)
+	b.SetBytes(int64(len(cipher)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.decipherMessage(cipher)
+	}
+}
+
+func BenchmarkDecipherBytes(b *testing.B) {
+	m, cipher := benchmarkCipher(b)
+	src := []byte(cipher)
+	dst := make([]byte, len(src))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.DecipherBytes(dst, src)
+	}
+}
+
+func BenchmarkEncipherBytesInPlace(b *testing.B) {
+	m, cipher := benchmarkCipher(b)
+	buf := []byte(cipher)
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.EncipherBytes(buf, buf)
+	}
+}
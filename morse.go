@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// morseCodes is International Morse for the characters found in PURPLE traffic. The hyphen
+// carries garble markers through transmission.
+var morseCodes = map[string]string{
+	"A": ".-", "B": "-...", "C": "-.-.", "D": "-..", "E": ".", "F": "..-.", "G": "--.",
+	"H": "....", "I": "..", "J": ".---", "K": "-.-", "L": ".-..", "M": "--", "N": "-.",
+	"O": "---", "P": ".--.", "Q": "--.-", "R": ".-.", "S": "...", "T": "-", "U": "..-",
+	"V": "...-", "W": ".--", "X": "-..-", "Y": "-.--", "Z": "--..",
+	"0": "-----", "1": ".----", "2": "..---", "3": "...--", "4": "....-",
+	"5": ".....", "6": "-....", "7": "--...", "8": "---..", "9": "----.",
+	".": ".-.-.-", ",": "--..--", "?": "..--..", "/": "-..-.", "=": "-...-", "-": "-....-",
+}
+
+// wabunCodes is Wabun code, the Japanese Morse code for katakana. Voiced and semi-voiced kana
+// are sent as the plain kana followed by the dakuten or handakuten mark.
+var wabunCodes = map[string]string{
+	"ア": "--.--", "イ": ".-", "ウ": "..-", "エ": "-.---", "オ": ".-...",
+	"カ": ".-..", "キ": "-.-..", "ク": "...-", "ケ": "-.--", "コ": "----",
+	"サ": "-.-.-", "シ": "--.-.", "ス": "---.-", "セ": ".---.", "ソ": "---.",
+	"タ": "-.", "チ": "..-.", "ツ": ".--.", "テ": ".-.--", "ト": "..-..",
+	"ナ": ".-.", "ニ": "-.-.", "ヌ": "....", "ネ": "--.-", "ノ": "..--",
+	"ハ": "-...", "ヒ": "--..-", "フ": "--..", "ヘ": ".", "ホ": "-..",
+	"マ": "-..-", "ミ": "..-.-", "ム": "-", "メ": "-...-", "モ": "-..-.",
+	"ヤ": ".--", "ユ": "-..--", "ヨ": "--",
+	"ラ": "...", "リ": "--.", "ル": "-.--.", "レ": "---", "ロ": ".-.-",
+	"ワ": "-.-", "ヰ": ".-..-", "ヱ": ".--..", "ヲ": ".---", "ン": ".-.-.",
+	"゛": "..", "゜": "..--.", "ー": ".--.-", "、": ".-.-.-", "。": ".-.-..",
+}
+
+// Voiced kana and the plain kana they are sent as, before a dakuten or handakuten.
+var (
+	wabunVoiced     = []rune("ガギグゲゴザジズゼゾダヂヅデドバビブベボ")
+	wabunUnvoiced   = []rune("カキクケコサシスセソタチツテトハヒフヘホ")
+	wabunSemiVoiced = []rune("パピプペポ")
+	wabunSemiBase   = []rune("ハヒフヘホ")
+)
+
+// morseWordGap separates words in Morse text; letters are separated by single spaces.
+const morseWordGap = " / "
+
+// morseEncode renders text as International Morse. Letters are case-insensitive and words are
+// separated by spaces or newlines.
+func morseEncode(text string) (string, error) {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		var codes []string
+		for _, c := range strings.ToUpper(word) {
+			code, ok := morseCodes[string(c)]
+			if !ok {
+				return "", fmt.Errorf("character %q has no International Morse code", c)
+			}
+			codes = append(codes, code)
+		}
+		words = append(words, strings.Join(codes, " "))
+	}
+	return strings.Join(words, morseWordGap), nil
+}
+
+// wabunEncode renders katakana or hiragana as Wabun code.
+func wabunEncode(text string) (string, error) {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		var codes []string
+		for _, c := range word {
+			if c >= 'ぁ' && c <= 'ゖ' {
+				c += 'ァ' - 'ぁ' // Hiragana to katakana
+			}
+			mark := ""
+			for i := range wabunVoiced {
+				if c == wabunVoiced[i] {
+					c, mark = wabunUnvoiced[i], "゛"
+				}
+			}
+			for i := range wabunSemiVoiced {
+				if c == wabunSemiVoiced[i] {
+					c, mark = wabunSemiBase[i], "゜"
+				}
+			}
+			code, ok := wabunCodes[string(c)]
+			if !ok {
+				return "", fmt.Errorf("character %q has no Wabun code", c)
+			}
+			codes = append(codes, code)
+			if mark != "" {
+				codes = append(codes, wabunCodes[mark])
+			}
+		}
+		words = append(words, strings.Join(codes, " "))
+	}
+	return strings.Join(words, morseWordGap), nil
+}
+
+// morseDecode reads International Morse back into text. A '?' stands for an element heard
+// but not identified as a dot or dash; it is resolved if only one character fits. A code that
+// fits no character is taken to have lost one element, and is resolved if only one character
+// fits with an element restored. Codes still matching no character, or more than one, become
+// '-' garbles. A lost element that leaves another valid code cannot be noticed.
+func morseDecode(morse string) string {
+	return decodeCodes(morse, morseCodes)
+}
+
+// wabunDecode reads Wabun code back into katakana, as morseDecode does for International Morse.
+// Voiced kana come out as the plain kana followed by a separate dakuten or handakuten.
+func wabunDecode(morse string) string {
+	return decodeCodes(morse, wabunCodes)
+}
+
+// decodeCodes decodes Morse-like text using table, which maps characters to codes.
+func decodeCodes(morse string, table map[string]string) string {
+	decode := make(map[string]string, len(table))
+	for c, code := range table {
+		decode[code] = c
+	}
+	var words []string
+	for _, word := range strings.Split(morse, "/") {
+		var b bytes.Buffer
+		for _, code := range strings.Fields(word) {
+			if c, ok := decode[code]; ok {
+				b.WriteString(c)
+				continue
+			}
+			c, n := morseCandidates(code, table, morseMatch)
+			if n == 0 {
+				c, n = morseCandidates(code, table, morseMatchDropped)
+			}
+			if n == 1 {
+				b.WriteString(c)
+			} else {
+				b.WriteByte('-')
+			}
+		}
+		if b.Len() > 0 {
+			words = append(words, b.String())
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// morseCandidates returns the number of characters in table whose codes match heard, and one
+// of them.
+func morseCandidates(heard string, table map[string]string, match func(heard, code string) bool) (string, int) {
+	found := ""
+	n := 0
+	for c, code := range table {
+		if match(heard, code) {
+			found = c
+			n++
+		}
+	}
+	return found, n
+}
+
+// morseMatch reports whether heard, which may contain '?' for unidentified elements, could be code.
+func morseMatch(heard, code string) bool {
+	if len(heard) != len(code) {
+		return false
+	}
+	for i := 0; i < len(heard); i++ {
+		if heard[i] != '?' && heard[i] != code[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// morseMatchDropped reports whether heard could be code with one element missed.
+func morseMatchDropped(heard, code string) bool {
+	if len(code) != len(heard)+1 {
+		return false
+	}
+	for i := range code {
+		if morseMatch(heard, code[:i]+code[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func runMorse(args []string) error {
+	fs := flag.NewFlagSet("morse", flag.ExitOnError)
+	decode := fs.Bool("decode", false, "decode Morse to text (default: encode text)")
+	wabun := fs.Bool("wabun", false, "use Wabun (kana) code instead of International Morse")
+	fs.Parse(args)
+
+	text, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *decode {
+		if *wabun {
+			fmt.Println(wabunDecode(text))
+		} else {
+			fmt.Println(morseDecode(text))
+		}
+		return nil
+	}
+	var morse string
+	if *wabun {
+		morse, err = wabunEncode(text)
+	} else {
+		morse, err = morseEncode(text)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(morse)
+	return nil
+}
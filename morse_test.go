@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestMorse(t *testing.T) {
+	for name, table := range map[string]map[string]string{"morse": morseCodes, "wabun": wabunCodes} {
+		seen := make(map[string]string)
+		for c, code := range table {
+			if other, ok := seen[code]; ok {
+				t.Errorf("%s codes for %s and %s are both %s", name, c, other, code)
+			}
+			seen[code] = c
+		}
+	}
+
+	morse, err := morseEncode("Sos\nZTX-D")
+	if err != nil {
+		t.Fatalf("morseEncode failed: %s", err)
+	}
+	if want := "... --- ... / --.. - -..- -....- -.."; morse != want {
+		t.Errorf("morseEncode gave %q, want %q", morse, want)
+	}
+	if _, err := morseEncode("A@"); err == nil {
+		t.Errorf("morseEncode with unsendable character should raise error")
+	}
+
+	// Transmit ciphertext and decipher what is received.
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	cipher := m.encipherMessage(englishSample[:120])
+	morse, _ = morseEncode(cipher)
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	if plain := m.decipherMessage(morseDecode(morse)); plain != englishSample[:120] {
+		t.Errorf("deciphered Morse transmission gave %s", plain)
+	}
+
+	var tests = []struct {
+		heard, want string
+	}{
+		{"... --- ...", "SOS"},
+		{"...?", "-"},           // H or V
+		{"--.?", "-"},           // Q or Z
+		{"-..-?", "/"},          // Only / fits
+		{"?", "-"},              // E or T
+		{".-.-.-.-", "-"},       // No such code
+		{"..-. / -.-? ", "F -"}, // C or Y
+		{"?--. ?---", "PJ"},     // Only P and J fit
+		{"--.--", ","},          // Comma with one dot dropped
+		{"-?.--", ","},          // And an element unidentified
+		{"---.", "-"},           // 8 or 9 with one element dropped
+		{".-", "A"},             // R with its last dot dropped cannot be told from A
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := morseDecode(test.heard); got != test.want {
+			t.Errorf("morseDecode(%q) = %q, want %q", test.heard, got, test.want)
+		}
+	}
+}
+
+func TestWabun(t *testing.T) {
+	morse, err := wabunEncode("ニイタカヤマ ノボレ")
+	if err != nil {
+		t.Fatalf("wabunEncode failed: %s", err)
+	}
+	if want := "-.-. .- -. .-.. .-- -..- / ..-- -.. .. ---"; morse != want {
+		t.Errorf("wabunEncode gave %q, want %q", morse, want)
+	}
+	if text := wabunDecode(morse); text != "ニイタカヤマ ノホ゛レ" {
+		t.Errorf("wabunDecode gave %q", text)
+	}
+	if hira, _ := wabunEncode("にいたかやま"); hira != "-.-. .- -. .-.. .-- -..-" {
+		t.Errorf("wabunEncode of hiragana gave %q", hira)
+	}
+	if _, err := wabunEncode("ABC"); err == nil {
+		t.Errorf("wabunEncode of Latin letters should raise error")
+	}
+	if text := wabunDecode("-.-. ?.?"); text != "ニ-" {
+		t.Errorf("wabunDecode of ambiguous code gave %q, want ニ-", text)
+	}
+}
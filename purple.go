@@ -1,5 +1,180 @@
 package main
 
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Settings of part 1 of the 14-part message, used when no key or alphabet is given.
+const (
+	defaultKey      = "9-1,24,6-23"
+	defaultAlphabet = "NOKTYUXEQLHBRMPDICJASVWGZF"
+)
+
+// command is one subcommand of the purple program.
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"armor":       {runArmor, "encipher any file as Base26 letters, or decipher it back"},
+	"baudot":      {runBaudot, "convert text to and from ITA2 (Baudot) teleprinter codes"},
+	"channel":     {runChannel, "send ciphertext through a noisy channel and measure decryption"},
+	"corpus":      {runCorpus, "check every message of a solved corpus deciphers correctly"},
+	"cycle":       {runCycle, "analyse the stepping cycle of the switches from a key"},
+	"decipher":    {runDecipher, "decipher ciphertext from the arguments or stdin"},
+	"depth":       {runDepth, "find depth and isomorphs in a directory of ciphertexts"},
+	"diff":        {runDiff, "compare deciphered text with expected plaintext, line by line"},
+	"encipher":    {runEncipher, "encipher plaintext from the arguments or stdin"},
+	"keys":        {runKeys, "list every key, or one shard of the key space"},
+	"keygen":      {runKeygen, "generate random keys and alphabets, or a daily key list"},
+	"morse":       {runMorse, "convert text to and from International or Wabun Morse code"},
+	"reconstruct": {runReconstruct, "infer unknown twenties wiring from known plaintext and ciphertext"},
+	"search":      {runSearch, "search the key space for a ciphertext, with checkpoints"},
+	"serve":       {runServe, "serve a local HTTP JSON API for encipher, decipher, validate and trace"},
+	"sim":         {runSimulator, "interactive simulator: type letters one at a time"},
+	"svg":         {runSVG, "draw the wiring, switch positions and path of each letter as SVG"},
+	"wav":         {runWAV, "key text as Morse audio in a WAV file, or decode such a file"},
+	"wiring":      {runWiring, "report cycle structure, repeats and differential statistics of switch wiring"},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: purple <command> [flags] [text]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'purple <command> -h' for the flags of a command.\n")
+}
+
+// jsMain, if set, runs instead of the command line; the js/wasm build sets it (see wasm.go).
+var jsMain func()
+
 func main() {
+	if jsMain != nil {
+		jsMain()
+		return
+	}
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "purple %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// keyFlags adds the -key and -alphabet flags to fs, for commands that need the values
+// themselves rather than a Machine.
+func keyFlags(fs *flag.FlagSet) (key, alphabet *string) {
+	key = fs.String("key", defaultKey, "switch settings, of the form 9-1,24,6-23")
+	alphabet = fs.String("alphabet", defaultAlphabet, "26-letter plugboard alphabet")
+	return key, alphabet
+}
+
+// machineFlags adds the -key and -alphabet flags to fs and returns a function that builds
+// a Machine from them once fs is parsed.
+func machineFlags(fs *flag.FlagSet) func() (*Machine, error) {
+	key, alphabet := keyFlags(fs)
+	return func() (*Machine, error) {
+		return NewMachineFromKey(*key, *alphabet)
+	}
+}
+
+// inputText returns the command-line arguments joined by spaces or, if there are none, all of stdin.
+func inputText(args []string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+	data, err := ioutil.ReadAll(bufio.NewReader(os.Stdin))
+	return string(data), err
+}
+
+func runEncipher(args []string) error {
+	return runCipher("encipher", args)
+}
+
+func runDecipher(args []string) error {
+	return runCipher("decipher", args)
+}
+
+// runCipher implements the encipher and decipher commands.
+func runCipher(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	trace := fs.Bool("trace", false, "write a trace of each letter to stderr")
+	format := fs.String("format", "table", "trace format: table or csv")
+	modeName := fs.String("mode", "preserve", "handling of case and non-letters: preserve, upper, strip or strict")
+	resync := new(bool)
+	if name == "decipher" {
+		resync = fs.Bool("resync", false, "correct for letters dropped from or inserted into the ciphertext, "+
+			"writing each correction to stderr")
+	}
+	fs.Parse(args)
 
+	// Check every flag before any output, so a bad one does not follow the text.
+	if err := checkTraceFormat(*format); err != nil {
+		return err
+	}
+	mode, err := ParseTextMode(*modeName)
+	if err != nil {
+		return err
+	}
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	text, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+	if mode == ModeStrict && len(fs.Args()) == 0 {
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r") // Final line end of stdin
+	}
+	var events []TraceEvent
+	if *trace {
+		m.SetTrace(func(e TraceEvent) {
+			events = append(events, e)
+		})
+	}
+	if name == "encipher" {
+		text, err = m.EncipherText(text, mode)
+	} else if *resync {
+		if text, err = mode.prepare(text); err == nil {
+			r := m.resyncDecipher(text, resyncWindow, resyncMaxShift)
+			text = r.Plaintext
+			for _, c := range r.Corrections {
+				fmt.Fprintf(os.Stderr, "resync: shift %+d at ciphertext offset %d\n", c.Shift, c.Offset)
+			}
+		}
+	} else {
+		text, err = m.DecipherText(text, mode)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Print(text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Println()
+	}
+	if *trace {
+		return writeTrace(os.Stderr, *format, events)
+	}
+	return nil
 }
@@ -0,0 +1,94 @@
+"""ctypes wrapper for the PURPLE emulator built as a C shared library.
+
+Build the library from the top of the repository with
+
+    go build -tags cshared -buildmode=c-shared -o libpurple.so
+
+and point PURPLE_LIB at it if it is not in the parent of this directory.
+
+    >>> m = Machine("9-1,24,6-23", "NOKTYUXEQLHBRMPDICJASVWGZF")
+    >>> m.decipher("ZTXODNWKCC")
+    'FOVTATAKID'
+
+A Machine is not safe to use from several threads at once.
+"""
+
+import ctypes
+import os
+
+_here = os.path.dirname(os.path.abspath(__file__))
+_lib = ctypes.CDLL(os.environ.get("PURPLE_LIB", os.path.join(_here, "..", "libpurple.so")))
+
+_lib.purple_new.argtypes = [ctypes.c_char_p, ctypes.c_char_p, ctypes.c_char_p, ctypes.c_int]
+_lib.purple_new.restype = ctypes.c_size_t
+for _f in (_lib.purple_encipher, _lib.purple_decipher):
+    _f.argtypes = [ctypes.c_size_t, ctypes.c_char_p, ctypes.c_char_p, ctypes.c_int]
+    _f.restype = ctypes.c_int
+_lib.purple_step.argtypes = [ctypes.c_size_t, ctypes.c_int]
+_lib.purple_step.restype = ctypes.c_int
+_lib.purple_state.argtypes = [ctypes.c_size_t] + [ctypes.POINTER(ctypes.c_int)] * 4
+_lib.purple_state.restype = ctypes.c_int
+_lib.purple_free.argtypes = [ctypes.c_size_t]
+_lib.purple_free.restype = None
+
+
+class Machine:
+    """A PURPLE machine set from a key such as '9-1,24,6-23' and a plugboard alphabet."""
+
+    def __init__(self, key, alphabet):
+        err = ctypes.create_string_buffer(256)
+        self._handle = _lib.purple_new(key.encode(), alphabet.encode(), err, len(err))
+        if not self._handle:
+            raise ValueError(err.value.decode())
+
+    def _run(self, f, text):
+        src = text.encode("latin-1")
+        dst = ctypes.create_string_buffer(len(src))
+        if f(self._check(), src, dst, len(src)) != 0:
+            raise ValueError("machine has been freed")
+        return dst.raw.decode("latin-1")
+
+    def _check(self):
+        if not self._handle:
+            raise ValueError("machine has been freed")
+        return self._handle
+
+    def encipher(self, text):
+        """Encipher text, stepping the machine as the Go encipherMessage does."""
+        return self._run(_lib.purple_encipher, text)
+
+    def decipher(self, text):
+        """Decipher text, stepping the machine as the Go decipherMessage does."""
+        return self._run(_lib.purple_decipher, text)
+
+    def step(self, n=1):
+        """Step the machine n times without a letter."""
+        _lib.purple_step(self._check(), n)
+
+    def state(self):
+        """Return the positions (1-25) of the sixes, fast, middle and slow switches."""
+        pos = [ctypes.c_int() for _ in range(4)]
+        _lib.purple_state(self._check(), *[ctypes.byref(p) for p in pos])
+        return tuple(p.value for p in pos)
+
+    def free(self):
+        """Release the machine in the library. It is also released when garbage collected."""
+        if self._handle:
+            _lib.purple_free(self._handle)
+            self._handle = 0
+
+    def __del__(self):
+        self.free()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.free()
+
+
+if __name__ == "__main__":
+    import sys
+
+    with Machine("9-1,24,6-23", "NOKTYUXEQLHBRMPDICJASVWGZF") as m:
+        print(m.decipher(sys.stdin.read() if len(sys.argv) < 2 else " ".join(sys.argv[1:])))
@@ -0,0 +1,57 @@
+"""Tests of the ctypes wrapper. Build libpurple.so first (see purple.py), then run
+
+    python3 -m unittest discover python
+"""
+
+import unittest
+
+from purple import Machine
+
+KEY = "9-1,24,6-23"
+ALPHABET = "NOKTYUXEQLHBRMPDICJASVWGZF"
+
+
+class TestMachine(unittest.TestCase):
+    def test_decipher(self):
+        with Machine(KEY, ALPHABET) as m:
+            self.assertEqual(m.decipher("ZTXODNWKCC"), "FOVTATAKID")
+            self.assertEqual(m.state(), (19, 9, 6, 1))
+
+    def test_round_trip(self):
+        plain = "Attack at dawn, 0600.\nConfirm."
+        with Machine(KEY, ALPHABET) as m:
+            cipher = m.encipher(plain)
+        self.assertNotEqual(cipher, plain)
+        self.assertEqual(len(cipher), len(plain))
+        with Machine(KEY, ALPHABET) as m:
+            self.assertEqual(m.decipher(cipher), plain)
+
+    def test_step(self):
+        with Machine(KEY, ALPHABET) as m:
+            self.assertEqual(m.state(), (9, 24, 6, 1))
+            m.step(3)
+            self.assertEqual(m.state()[0], 12)
+
+    def test_bad_key(self):
+        with self.assertRaisesRegex(ValueError, "must be different"):
+            Machine("9-1,24,6-11", ALPHABET)
+        with self.assertRaises(ValueError):
+            Machine(KEY, "ABC")
+
+    def test_free(self):
+        m = Machine(KEY, ALPHABET)
+        m.free()
+        m.free()
+        with self.assertRaises(ValueError):
+            m.decipher("A")
+
+    def test_independent(self):
+        a = Machine(KEY, ALPHABET)
+        b = Machine(KEY, ALPHABET)
+        a.step(5)
+        self.assertNotEqual(a.state(), b.state())
+        self.assertEqual(b.decipher("ZTXODNWKCC"), "FOVTATAKID")
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partialWiring is a switch's decipher wiring (as in switchData, 0-based) with unknown entries
+// set to -1.
+type partialWiring [][]int
+
+func newPartialWiring(npositions, nlevels int) partialWiring {
+	w := make(partialWiring, npositions)
+	for i := range w {
+		w[i] = make([]int, nlevels)
+		for j := range w[i] {
+			w[i][j] = -1
+		}
+	}
+	return w
+}
+
+// set records that at position pos, level out deciphers to level in. It returns an error if
+// that contradicts what is already known.
+func (w partialWiring) set(pos, out, in int) error {
+	row := w[pos]
+	if row[out] == in {
+		return nil
+	}
+	if row[out] >= 0 {
+		return fmt.Errorf("position %d level %d deciphers to both %d and %d", pos+1, out+1, row[out]+1, in+1)
+	}
+	for j, v := range row {
+		if v == in {
+			return fmt.Errorf("position %d levels %d and %d both decipher to %d", pos+1, j+1, out+1, in+1)
+		}
+	}
+	row[out] = in
+	return nil
+}
+
+// complete fills in the last entry of every row with only one unknown, since each row is a
+// permutation. It returns the number of entries filled.
+func (w partialWiring) complete() int {
+	filled := 0
+	for _, row := range w {
+		missing := -1
+		used := make([]bool, len(row))
+		for j, v := range row {
+			if v < 0 {
+				if missing >= 0 {
+					missing = -2
+					break
+				}
+				missing = j
+			} else {
+				used[v] = true
+			}
+		}
+		if missing < 0 {
+			continue
+		}
+		for v, u := range used {
+			if !u {
+				row[missing] = v
+				filled++
+			}
+		}
+	}
+	return filled
+}
+
+// unknown returns the number of unknown entries.
+func (w partialWiring) unknown() int {
+	n := 0
+	for _, row := range w {
+		for _, v := range row {
+			if v < 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// write saves w in the layout of the tables in switch.go: one line per position, entries 1-based,
+// with '?' for unknown entries.
+func (w partialWiring) write(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	for _, row := range w {
+		for j, v := range row {
+			if j > 0 {
+				bw.WriteByte(' ')
+			}
+			if v < 0 {
+				bw.WriteByte('?')
+			} else {
+				bw.WriteString(strconv.Itoa(v + 1))
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// readPartialWiring reads a wiring in the form written by partialWiring.write. Every row is
+// checked as partialWiring.set checks new entries, so no two levels of a row may decipher to
+// the same level.
+func readPartialWiring(r io.Reader) (partialWiring, error) {
+	var w partialWiring
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(w) > 0 && len(fields) != len(w[0]) {
+			return nil, fmt.Errorf("wiring line %d has %d entries, want %d", len(w)+1, len(fields), len(w[0]))
+		}
+		pos := len(w)
+		w = append(w, newPartialWiring(1, len(fields))[0])
+		for j, f := range fields {
+			if f == "?" {
+				continue
+			}
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, err
+			}
+			if v < 1 || v > len(fields) {
+				return nil, fmt.Errorf("wiring line %d has entry %d, must be in [1,%d]", pos+1, v, len(fields))
+			}
+			if err := w.set(pos, j, v-1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return w, scanner.Err()
+}
+
+// reconstructTwenties infers entries of the wiring of twenties switch number sw (1-3) from
+// plain and cipher, a pair of aligned texts enciphered on m from its current state. The other
+// two twenties switches are assumed to have the standard wiring. Each letter that takes the
+// twenties path gives one entry of w at the position sw had at that moment. Garbles ('-') and
+// other non-letters are skipped, though the machine steps as in decipherMessage. Contradictions,
+// which suggest a wrong key, plugboard or garbled letter, are returned without being recorded.
+// The machine m is stepped to the end of the text.
+func (m *Machine) reconstructTwenties(plain, cipher string, sw int, w partialWiring) ([]error, error) {
+	if len(plain) != len(cipher) {
+		return nil, fmt.Errorf("plaintext has %d characters, ciphertext %d; they must align", len(plain), len(cipher))
+	}
+	if sw < 1 || sw > 3 {
+		return nil, fmt.Errorf("twenties switch = %d, must be in [1,3]", sw)
+	}
+	k := sw - 1
+	var conflicts []error
+	for i := 0; i < len(plain); i++ {
+		p, c := plain[i]&^0x20, cipher[i]&^0x20
+		if p >= 'A' && p <= 'Z' && c >= 'A' && c <= 'Z' {
+			n, cn := int(m.plugboardIn[p-'A']), int(m.plugboardIn[c-'A'])
+			if (n < 6) != (cn < 6) {
+				conflicts = append(conflicts, fmt.Errorf("offset %d: %c and %c are not on the same path", i, p, c))
+			} else if n >= 6 {
+				x, y := byte(n-6), byte(cn-6)
+				for j := 0; j < k; j++ {
+					x = m.twenties[j].encipher(x)
+				}
+				for j := 2; j > k; j-- {
+					y = m.twenties[j].decipher(y)
+				}
+				if err := w.set(m.twenties[k].position, int(y), int(x)); err != nil {
+					conflicts = append(conflicts, fmt.Errorf("offset %d: %s", i, err))
+				}
+			}
+		}
+		if cipher[i] != ' ' && cipher[i] != '\n' {
+			m.step()
+		}
+	}
+	return conflicts, nil
+}
+
+// role names the job (fast, middle or slow) of twenties switch s of m.
+func (m *Machine) role(s *Switch) string {
+	switch s {
+	case m.fast:
+		return "fast"
+	case m.middle:
+		return "middle"
+	}
+	return "slow"
+}
+
+func runReconstruct(args []string) error {
+	fs := flag.NewFlagSet("reconstruct", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	sw := fs.Int("switch", 1, "twenties switch (1-3) whose wiring is unknown")
+	plainFile := fs.String("plain", "", "file of known plaintext (required)")
+	cipherFile := fs.String("cipher", "", "file of the matching ciphertext (required)")
+	in := fs.String("in", "", "partial wiring file to extend, from an earlier run")
+	out := fs.String("o", "", "write the partial wiring to this file instead of stdout")
+	fs.Parse(args)
+
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	if *plainFile == "" || *cipherFile == "" {
+		return fmt.Errorf("both -plain and -cipher are required")
+	}
+	plain, err := ioutil.ReadFile(*plainFile)
+	if err != nil {
+		return err
+	}
+	cipher, err := ioutil.ReadFile(*cipherFile)
+	if err != nil {
+		return err
+	}
+	w := newPartialWiring(25, 20)
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		w, err = readPartialWiring(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if len(w) != 25 || len(w[0]) != 20 {
+			return fmt.Errorf("%s is not a 25x20 twenties wiring", *in)
+		}
+	}
+
+	conflicts, err := m.reconstructTwenties(string(plain), string(cipher), *sw, w)
+	if err != nil {
+		return err
+	}
+	role := m.role(m.twenties[*sw-1])
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+	}
+	filled := w.complete()
+	fmt.Fprintf(os.Stderr, "twenties switch %d (the %s switch): %d of 500 entries unknown, %d deduced from complete rows\n",
+		*sw, role, w.unknown(), filled)
+	for pos, row := range w {
+		var missing []string
+		for level, v := range row {
+			if v < 0 {
+				missing = append(missing, strconv.Itoa(level+1))
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "  position %2d unknown levels: %s\n", pos+1, strings.Join(missing, " "))
+		}
+	}
+
+	if *out == "" {
+		return w.write(os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := w.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
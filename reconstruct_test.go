@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReconstructTwenties(t *testing.T) {
+	plain := strings.Repeat(englishSample, 8)
+	m, err := NewMachineFromKey(defaultKey, defaultAlphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	cipher := m.encipherMessage(plain)
+
+	for sw, want := range []*Switch{twenties1, twenties2, twenties3} {
+		m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+		w := newPartialWiring(25, 20)
+		conflicts, err := m.reconstructTwenties(plain, cipher, sw+1, w)
+		if err != nil || len(conflicts) > 0 {
+			t.Fatalf("reconstructTwenties(switch %d) gave error %v, conflicts %v", sw+1, err, conflicts)
+		}
+		w.complete()
+		known := 0
+		for pos, row := range w {
+			for level, v := range row {
+				if v < 0 {
+					continue
+				}
+				known++
+				if byte(v) != want.decipherWiring[pos][level] {
+					t.Errorf("switch %d position %d level %d reconstructed as %d, want %d",
+						sw+1, pos, level, v, want.decipherWiring[pos][level])
+				}
+			}
+		}
+		if known+w.unknown() != 500 || known < 100 {
+			t.Errorf("switch %d: reconstructed %d entries, %d unknown", sw+1, known, w.unknown())
+		}
+	}
+
+	// A wrong plaintext letter should be reported as a conflict, not recorded.
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	w := newPartialWiring(25, 20)
+	bad := "Q" + plain[1:]
+	if conflicts, _ := m.reconstructTwenties(bad+bad, cipher+cipher, 2, w); len(conflicts) == 0 {
+		t.Errorf("reconstructTwenties found no conflicts in inconsistent traffic")
+	}
+	if _, err := m.reconstructTwenties("AB", "A", 2, w); err == nil {
+		t.Errorf("reconstructTwenties with unaligned texts should raise error")
+	}
+	if _, err := m.reconstructTwenties("A", "A", 4, w); err == nil {
+		t.Errorf("reconstructTwenties with switch 4 should raise error")
+	}
+}
+
+func TestPartialWiringIO(t *testing.T) {
+	w := newPartialWiring(3, 4)
+	w.set(0, 0, 2)
+	w.set(0, 1, 0)
+	w.set(0, 2, 3)
+	w.set(1, 3, 1)
+	if err := w.set(1, 2, 1); err == nil {
+		t.Errorf("partialWiring.set should reject a repeated level")
+	}
+	if err := w.set(1, 3, 2); err == nil {
+		t.Errorf("partialWiring.set should reject a changed entry")
+	}
+	if filled := w.complete(); filled != 1 || w[0][3] != 1 {
+		t.Errorf("partialWiring.complete filled %d entries, row 0 = %v", filled, w[0])
+	}
+
+	var buf bytes.Buffer
+	if err := w.write(&buf); err != nil {
+		t.Fatalf("partialWiring.write failed: %s", err)
+	}
+	want := "3 1 4 2\n? ? ? 2\n? ? ? ?\n"
+	if buf.String() != want {
+		t.Errorf("partialWiring.write gave %q, want %q", buf.String(), want)
+	}
+	r, err := readPartialWiring(&buf)
+	if err != nil {
+		t.Fatalf("readPartialWiring failed: %s", err)
+	}
+	if len(r) != 3 || r.unknown() != w.unknown() || r[0][3] != 1 || r[1][3] != 1 {
+		t.Errorf("readPartialWiring gave %v, want %v", r, w)
+	}
+	if _, err := readPartialWiring(strings.NewReader("1 2\n1 2 3\n")); err == nil {
+		t.Errorf("readPartialWiring with ragged lines should raise error")
+	}
+	if _, err := readPartialWiring(strings.NewReader("1 5\n")); err == nil {
+		t.Errorf("readPartialWiring with out-of-range entry should raise error")
+	}
+	if _, err := readPartialWiring(strings.NewReader("1 2 ?\n3 ? 3\n")); err == nil {
+		t.Errorf("readPartialWiring with repeated entry should raise error")
+	}
+}
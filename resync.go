@@ -0,0 +1,138 @@
+package main
+
+// Correction records one change of alignment made while resynchronising a message.
+type Correction struct {
+	Offset int // Index into the ciphertext where the correction applies
+	Shift  int // >0: Shift letters were lost, so the machine was stepped ahead; <0: -Shift inserted letters were skipped
+}
+
+// ResyncResult holds the output of resyncDecipher.
+type ResyncResult struct {
+	Plaintext   string
+	Corrections []Correction
+}
+
+const (
+	resyncWindow    = 30   // Letters of plaintext examined to decide whether the alignment is lost
+	resyncMaxShift  = 3    // Largest number of dropped or inserted letters tried at one point
+	resyncThreshold = -0.1 // scoreEnglish value below which plaintext is considered garbage
+)
+
+// resyncDecipher deciphers cipher like decipherMessage, but watches the quality of the
+// plaintext. When the last window letters stop reading as English, it tries stepping the machine
+// ahead (for letters dropped from the intercept) or skipping ciphertext (for inserted letters)
+// by up to maxShift at each point in that window, and keeps the alignment that reads best.
+// Letters assumed lost appear as '-' garbles in the plaintext. The machine is left in the state
+// after the last letter, as with decipherMessage. If m is traced, only the letters kept in the
+// plaintext are reported, by their index in cipher.
+func (m *Machine) resyncDecipher(cipher string, window, maxShift int) ResyncResult {
+	var result ResyncResult
+	w := m.clone()
+	src := []byte(cipher)
+	one := make([]byte, 1) // Each byte is deciphered into this, to save allocating a string
+	out := make([]byte, 0, len(cipher))
+	states := make([]State, len(cipher)) // Machine state before each ciphertext byte
+	outlen := make([]int, len(cipher))   // Length of out before each ciphertext byte
+	var letters []int                    // Ciphertext indices of the letters deciphered so far
+	floor := 0                           // No correction may be placed before this index
+	quiet := window                      // Don't look for a collapse until this many letters are seen
+
+	isLetter := func(c byte) bool {
+		return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+	}
+
+	for i := 0; i < len(cipher); i++ {
+		states[i] = w.state()
+		outlen[i] = len(out)
+		w.DecipherBytes(one, src[i:i+1])
+		out = append(out, one[0])
+		if !isLetter(cipher[i]) {
+			continue
+		}
+		letters = append(letters, i)
+		if len(letters) < quiet {
+			continue
+		}
+		start := letters[len(letters)-window]
+		if scoreEnglish(string(out[outlen[start]:])) >= resyncThreshold {
+			continue
+		}
+
+		// The alignment seems lost somewhere in cipher[start:i+1]. Score each candidate
+		// correction over the window plus as many letters again after it.
+		if start < floor {
+			start = floor
+		}
+		end := i + 1
+		for n := 0; end < len(cipher) && n < window; end++ {
+			if isLetter(cipher[end]) {
+				n++
+			}
+		}
+		trial := m.clone()
+		try := func(j, shift int) float64 {
+			trial.setState(states[j])
+			for k := 0; k < shift; k++ {
+				trial.step()
+			}
+			text := string(out[outlen[start]:outlen[j]])
+			if shift < 0 {
+				j -= shift
+			}
+			if j < end {
+				text += trial.decipherMessage(cipher[j:end])
+			}
+			return scoreEnglish(text)
+		}
+		bestScore := try(start, 0)
+		bestJ, bestShift := start, 0
+		for j := start; j <= i; j++ {
+			for shift := -maxShift; shift <= maxShift; shift++ {
+				if shift == 0 || j-shift > len(cipher) {
+					continue
+				}
+				if s := try(j, shift); s > bestScore {
+					bestScore, bestJ, bestShift = s, j, shift
+				}
+			}
+		}
+		if bestShift == 0 || bestScore < resyncThreshold {
+			quiet = len(letters) + window
+			continue
+		}
+
+		// Rewind to the chosen point and apply the correction.
+		result.Corrections = append(result.Corrections, Correction{bestJ, bestShift})
+		out = out[:outlen[bestJ]]
+		for len(letters) > 0 && letters[len(letters)-1] >= bestJ {
+			letters = letters[:len(letters)-1]
+		}
+		w.setState(states[bestJ])
+		next := bestJ
+		if bestShift > 0 {
+			for k := 0; k < bestShift; k++ {
+				w.step()
+				out = append(out, '-')
+			}
+		} else {
+			for k := bestJ; k < bestJ-bestShift; k++ {
+				states[k] = w.state()
+				outlen[k] = len(out)
+			}
+			next = bestJ - bestShift
+		}
+		floor = next
+		quiet = len(letters) + window
+		i = next - 1
+	}
+	m.setState(w.state())
+	if m.trace != nil {
+		w.trace = m.trace
+		for _, i := range letters {
+			w.setState(states[i])
+			w.traceLetter(i, false, cipher[i], out[outlen[i]])
+		}
+	}
+	result.Plaintext = string(out)
+	return result
+}
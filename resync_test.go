@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// englishSample is ordinary English prose used to test scoring and resynchronisation.
+const englishSample = `THEGOVERNMENTOFJAPANPROMPTEDBYAGENUINEDESIRETOCOMETOANAMICABLEUNDERSTANDING` +
+	`WITHTHEGOVERNMENTOFTHEUNITEDSTATESINORDERTHATTHETWOCOUNTRIESBYTHEIRJOINTEFFORTS` +
+	`MAYSECURETHEPEACEOFTHEPACIFICAREAANDTHEREBYCONTRIBUTETOWARDTHEREALIZATIONOFWORLD` +
+	`PEACEHASCONTINUEDNEGOTIATIONSWITHTHEUTMOSTSINCERITYSINCEAPRILLASTWITHTHEGOVERNMENT` +
+	`OFTHEUNITEDSTATESREGARDINGTHEADJUSTMENTANDADVANCEMENTOFJAPANESEAMERICANRELATIONS`
+
+func TestScoreEnglish(t *testing.T) {
+	if s := scoreEnglish(englishSample); s < 0.2 {
+		t.Errorf("scoreEnglish(English) = %.3f, want > 0.2", s)
+	}
+	m, err := NewMachineFromKey("9-1,24,6-23", "NOKTYUXEQLHBRMPDICJASVWGZF")
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err.Error())
+	}
+	if s := scoreEnglish(m.encipherMessage(englishSample)); s > -0.2 {
+		t.Errorf("scoreEnglish(ciphertext) = %.3f, want < -0.2", s)
+	}
+	if s := scoreEnglish("  --  "); s != 0 {
+		t.Errorf("scoreEnglish(no letters) = %.3f, want 0", s)
+	}
+}
+
+func TestResync(t *testing.T) {
+	key := "9-1,24,6-23"
+	alphabet := "NOKTYUXEQLHBRMPDICJASVWGZF"
+	m, err := NewMachineFromKey(key, alphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine from key, alphabet: %s, %s", key, alphabet)
+	}
+	cipher := m.encipherMessage(englishSample)
+
+	var tests = []struct {
+		garbled string
+		offset  int
+		shift   int
+	}{
+		{cipher, 0, 0},
+		{cipher[:100] + cipher[101:], 100, 1},
+		{cipher[:150] + cipher[152:], 150, 2},
+		{cipher[:120] + "Q" + cipher[120:], 120, -1},
+		{cipher[:200] + "XY" + cipher[200:], 200, -2},
+	}
+	for _, test := range tests {
+		m, _ = NewMachineFromKey(key, alphabet)
+		result := m.resyncDecipher(test.garbled, resyncWindow, resyncMaxShift)
+		if test.shift == 0 {
+			if len(result.Corrections) != 0 || result.Plaintext != englishSample {
+				t.Errorf("resyncDecipher made corrections %v to an intact message", result.Corrections)
+			}
+			continue
+		}
+		if len(result.Corrections) != 1 {
+			t.Errorf("resyncDecipher made corrections %v, want 1 at offset %d", result.Corrections, test.offset)
+			continue
+		}
+		c := result.Corrections[0]
+		if c.Shift != test.shift || c.Offset < test.offset-8 || c.Offset > test.offset+8 {
+			t.Errorf("resyncDecipher made correction %v, want shift %d near offset %d", c, test.shift, test.offset)
+		}
+		// Apart from a few letters near the correction, the plaintext should be recovered.
+		tail := englishSample[test.offset+10:]
+		if !strings.HasSuffix(result.Plaintext, tail) {
+			t.Errorf("resyncDecipher did not recover plaintext after the correction:\n%s", result.Plaintext)
+		}
+	}
+}
+
+func TestResyncTrace(t *testing.T) {
+	m, err := NewMachineFromKey("9-1,24,6-23", "NOKTYUXEQLHBRMPDICJASVWGZF")
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err.Error())
+	}
+	cipher := m.clone().encipherMessage(englishSample)
+	garbled := cipher[:100] + cipher[101:]
+
+	var events []TraceEvent
+	m.SetTrace(func(e TraceEvent) {
+		events = append(events, e)
+	})
+	result := m.resyncDecipher(garbled, resyncWindow, resyncMaxShift)
+	if len(result.Corrections) != 1 {
+		t.Fatalf("resyncDecipher made corrections %v, want 1", result.Corrections)
+	}
+	kept := len(result.Plaintext) - strings.Count(result.Plaintext, "-")
+	if len(events) != kept {
+		t.Errorf("resyncDecipher traced %d letters, want one per letter kept, %d", len(events), kept)
+	}
+	for i, e := range events {
+		if e.Encipher || e.Input != garbled[e.Index] || (i > 0 && e.Index <= events[i-1].Index) {
+			t.Errorf("event %d is %+v, out of order or not from the ciphertext", i, e)
+			break
+		}
+	}
+}
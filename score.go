@@ -0,0 +1,40 @@
+package main
+
+import "math"
+
+// englishFreq holds the relative frequency (percent) of each letter A-Z in English text.
+var englishFreq = [26]float64{
+	8.167, 1.492, 2.782, 4.253, 12.702, 2.228, 2.015, 6.094, 6.966, 0.153, 0.772, 4.025, 2.406,
+	6.749, 7.507, 1.929, 0.095, 5.987, 6.327, 9.056, 2.758, 0.978, 2.360, 0.150, 1.974, 0.074,
+}
+
+// letterScore is the log-likelihood ratio of each letter under English versus uniformly
+// random text.
+var letterScore [26]float64
+
+func init() {
+	for i, f := range englishFreq {
+		letterScore[i] = math.Log(26 * f / 100)
+	}
+}
+
+// scoreEnglish returns the mean per-letter log-likelihood ratio of text being English rather
+// than random letters. English text scores near +0.35, random text near -0.5. Non-letters are
+// ignored; text with no letters scores 0.
+func scoreEnglish(text string) float64 {
+	total := 0.0
+	n := 0
+	for _, c := range []byte(text) {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c >= 'A' && c <= 'Z' {
+			total += letterScore[c-'A']
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+)
+
+// searchCandidate is one key found by a search, with the score of its plaintext.
+type searchCandidate struct {
+	Key   string  `json:"key"`
+	Score float64 `json:"score"`
+}
+
+// searchCheckpoint records the progress of a key search through one shard of the key space.
+type searchCheckpoint struct {
+	Cipher   string            `json:"cipher_sha256"` // Identifies the ciphertext being searched
+	Alphabet string            `json:"alphabet"`
+	Shard    int               `json:"shard"`
+	Shards   int               `json:"shards"`
+	LastKey  string            `json:"last_key"` // Every key of the shard up to this one has been tried
+	Tried    int               `json:"tried"`
+	Best     []searchCandidate `json:"best"` // Highest scores first
+	Done     bool              `json:"done"`
+}
+
+func cipherHash(cipher string) string {
+	h := sha256.Sum256([]byte(cipher))
+	return hex.EncodeToString(h[:])
+}
+
+// addCandidate inserts c into cp.Best, keeping at most n candidates and no duplicate keys.
+func (cp *searchCheckpoint) addCandidate(c searchCandidate, n int) {
+	for _, b := range cp.Best {
+		if b.Key == c.Key {
+			return
+		}
+	}
+	i := sort.Search(len(cp.Best), func(i int) bool { return cp.Best[i].Score < c.Score })
+	if i >= n {
+		return
+	}
+	cp.Best = append(cp.Best, searchCandidate{})
+	copy(cp.Best[i+1:], cp.Best[i:])
+	cp.Best[i] = c
+	if len(cp.Best) > n {
+		cp.Best = cp.Best[:n]
+	}
+}
+
+// saveCheckpoint writes cp as JSON to filename, replacing it only once the new file is complete.
+func saveCheckpoint(filename string, cp *searchCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func loadCheckpoint(filename string) (*searchCheckpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cp := new(searchCheckpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return cp, nil
+}
+
+// stoppableKeys is a keySource that ends early once stop is closed.
+type stoppableKeys struct {
+	keys keySource
+	stop <-chan struct{}
+}
+
+func (s stoppableKeys) nextKey() (string, bool) {
+	select {
+	case <-s.stop:
+		return "", false
+	default:
+		return s.keys.nextKey()
+	}
+}
+
+// keySearch deciphers cipher with every key from it, keeping the nbest highest-scoring keys in
+// cp. It calls save with the checkpoint at least every interval and once at the end. Keys
+// finish out of order, so cp.LastKey only advances past keys whose predecessors are all done.
+// Closing stop ends the search early, after the keys already started; cp.Done is set only if
+// the iterator was exhausted.
+func keySearch(cipher string, it *keyIterator, cp *searchCheckpoint, nbest, workers int,
+	interval time.Duration, save func(*searchCheckpoint) error, stop <-chan struct{}) error {
+	quit := make(chan struct{})
+	defer close(quit)
+	results, err := batchDecipher(cipher, cp.Alphabet, stoppableKeys{it, stop}, workers, quit)
+	if err != nil {
+		return err
+	}
+	finished := make(map[int]string) // Results not yet contiguous with LastKey
+	done := 0
+	lastSave := time.Now()
+	for r := range results {
+		if r.Err != nil {
+			return r.Err
+		}
+		cp.addCandidate(searchCandidate{r.Key, r.Score}, nbest)
+		finished[r.Index] = r.Key
+		for key, ok := finished[done]; ok; key, ok = finished[done] {
+			delete(finished, done)
+			cp.LastKey = key
+			cp.Tried++
+			done++
+		}
+		if time.Since(lastSave) >= interval {
+			if err := save(cp); err != nil {
+				return err
+			}
+			lastSave = time.Now()
+		}
+	}
+	cp.Done = it.remaining() == 0 && len(finished) == 0
+	return save(cp)
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	alphabet := fs.String("alphabet", defaultAlphabet, "26-letter plugboard alphabet, assumed known")
+	cipherFile := fs.String("cipher", "", "file of ciphertext to search keys for (required)")
+	shard := fs.Int("shard", 0, "which shard of the key space to search, from 0")
+	shards := fs.Int("shards", 1, "number of shards the key space is split into")
+	checkpoint := fs.String("checkpoint", "search.json", "checkpoint file, resumed from if it exists")
+	interval := fs.Duration("every", 30*time.Second, "how often to write the checkpoint")
+	nbest := fs.Int("best", 10, "number of best candidates to keep")
+	workers := fs.Int("workers", 0, "goroutines to use (default: one per CPU)")
+	fs.Parse(args)
+
+	if *cipherFile == "" {
+		return fmt.Errorf("-cipher is required")
+	}
+	data, err := ioutil.ReadFile(*cipherFile)
+	if err != nil {
+		return err
+	}
+	cipher := string(data)
+	it, err := newKeyIterator(*shard, *shards)
+	if err != nil {
+		return err
+	}
+
+	cp := &searchCheckpoint{Cipher: cipherHash(cipher), Alphabet: *alphabet, Shard: *shard, Shards: *shards}
+	if old, err := loadCheckpoint(*checkpoint); err == nil {
+		if old.Cipher != cp.Cipher || old.Alphabet != cp.Alphabet || old.Shard != cp.Shard || old.Shards != cp.Shards {
+			return fmt.Errorf("%s is for a different ciphertext, alphabet or shard", *checkpoint)
+		}
+		cp = old
+		if cp.LastKey != "" {
+			if err := it.resumeAfter(cp.LastKey); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "resuming after %s: %d tried, %d to go\n", cp.LastKey, cp.Tried, it.remaining())
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	stop := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			fmt.Fprintf(os.Stderr, "interrupted; finishing keys in progress and saving %s "+
+				"(interrupt again to quit at once)\n", *checkpoint)
+			close(stop)
+			signal.Stop(interrupt) // A second interrupt kills the search as usual
+		}
+	}()
+
+	save := func(cp *searchCheckpoint) error {
+		return saveCheckpoint(*checkpoint, cp)
+	}
+	if err := keySearch(cipher, it, cp, *nbest, *workers, *interval, save, stop); err != nil {
+		return err
+	}
+	if !cp.Done {
+		fmt.Fprintf(os.Stderr, "stopped after %s; run again to resume\n", cp.LastKey)
+	}
+	for _, c := range cp.Best {
+		fmt.Printf("%8.4f %s\n", c.Score, c.Key)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeySearch(t *testing.T) {
+	key := "2-1,3,5-31"
+	m, err := NewMachineFromKey(key, defaultAlphabet)
+	if err != nil {
+		t.Fatalf("Could not make machine: %s", err)
+	}
+	cipher := m.encipherMessage(englishSample[:120])
+	target, _ := keyIndex(key)
+
+	dir, err := ioutil.TempDir("", "purple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "search.json")
+	save := func(cp *searchCheckpoint) error {
+		return saveCheckpoint(filename, cp)
+	}
+
+	// Search part of the range, stopping once 200 keys are done. With a zero interval save is
+	// called after every result, so it can close stop in the middle of the run.
+	start := target - 500
+	it := &keyIterator{start, start, target + 500}
+	cp := &searchCheckpoint{Cipher: cipherHash(cipher), Alphabet: defaultAlphabet}
+	stop := make(chan struct{})
+	stopped := false
+	stopAfter := func(cp *searchCheckpoint) error {
+		if cp.Tried >= 200 && !stopped {
+			close(stop)
+			stopped = true
+		}
+		return save(cp)
+	}
+	if err := keySearch(cipher, it, cp, 3, 2, 0, stopAfter, stop); err != nil {
+		t.Fatalf("keySearch failed: %s", err)
+	}
+
+	// The checkpoint must record every key up to LastKey, and no others, as tried.
+	cp, err = loadCheckpoint(filename)
+	if err != nil {
+		t.Fatalf("loadCheckpoint failed: %s", err)
+	}
+	if cp.Done || cp.Tried < 200 || cp.Tried >= 1000 {
+		t.Fatalf("interrupted keySearch says done %v after %d keys, want not done after 200-999", cp.Done, cp.Tried)
+	}
+	if want := keyAt(start + cp.Tried - 1); cp.LastKey != want {
+		t.Errorf("interrupted keySearch saved last key %s after %d keys, want %s", cp.LastKey, cp.Tried, want)
+	}
+
+	// Resume from the checkpoint file and finish.
+	tried := cp.Tried
+	it = &keyIterator{start, start, target + 500}
+	if err := it.resumeAfter(cp.LastKey); err != nil {
+		t.Fatalf("resumeAfter(%s) failed: %s", cp.LastKey, err)
+	}
+	if it.remaining() != 1000-tried {
+		t.Errorf("resumed iterator has %d keys to go after %d tried, want %d", it.remaining(), tried, 1000-tried)
+	}
+	if err := keySearch(cipher, it, cp, 3, 2, time.Hour, save, make(chan struct{})); err != nil {
+		t.Fatalf("keySearch failed: %s", err)
+	}
+	cp, err = loadCheckpoint(filename)
+	if err != nil {
+		t.Fatalf("loadCheckpoint failed: %s", err)
+	}
+	if !cp.Done || cp.Tried != 1000 || cp.LastKey != keyAt(target+499) {
+		t.Errorf("resumed search done %v, tried %d, last key %s; want true, 1000, %s", cp.Done, cp.Tried, cp.LastKey, keyAt(target+499))
+	}
+	if len(cp.Best) != 3 || cp.Best[0].Key != key || cp.Best[0].Score < cp.Best[1].Score {
+		t.Errorf("search found best candidates %+v, want %s first", cp.Best, key)
+	}
+}
+
+func TestAddCandidate(t *testing.T) {
+	var cp searchCheckpoint
+	for _, c := range []searchCandidate{{"a", 1}, {"b", 3}, {"c", 2}, {"b", 3}, {"d", 0}, {"e", 5}} {
+		cp.addCandidate(c, 3)
+	}
+	want := []searchCandidate{{"e", 5}, {"b", 3}, {"c", 2}}
+	if len(cp.Best) != len(want) {
+		t.Fatalf("addCandidate kept %v, want %v", cp.Best, want)
+	}
+	for i := range want {
+		if cp.Best[i] != want[i] {
+			t.Errorf("addCandidate kept %v, want %v", cp.Best, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// apiRequest is the JSON body accepted by every endpoint of the HTTP API.
+type apiRequest struct {
+	Key      string `json:"key"`
+	Alphabet string `json:"alphabet"`
+	Text     string `json:"text"`
+	Mode     string `json:"mode"` // For /trace: "encipher" or "decipher" (the default)
+}
+
+// apiTraceStep is the JSON form of a TraceEvent. Switch positions are 1-25, as in keys.
+type apiTraceStep struct {
+	Index  int    `json:"index"`
+	Input  string `json:"input"`
+	Plug   int    `json:"plug"`
+	Path   string `json:"path"`
+	Sixes  int    `json:"sixes"`
+	Fast   int    `json:"fast"`
+	Middle int    `json:"middle"`
+	Slow   int    `json:"slow"`
+	Output string `json:"output"`
+}
+
+// apiResponse is the JSON body returned by the HTTP API. Fields that don't apply are omitted.
+type apiResponse struct {
+	Text  string         `json:"text,omitempty"`
+	Valid *bool          `json:"valid,omitempty"` // Set only by /validate
+	Trace []apiTraceStep `json:"trace,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// apiServer serves the HTTP API. Each request builds its own Machine, so requests are independent.
+type apiServer struct {
+	maxBytes int64  // Largest request body accepted
+	static   string // Directory of files to serve at other paths, if not ""
+}
+
+// handler returns an http.Handler with all the API endpoints.
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/encipher", s.endpoint(func(m *Machine, req apiRequest) apiResponse {
+		return apiResponse{Text: m.encipherMessage(req.Text)}
+	}))
+	mux.HandleFunc("/decipher", s.endpoint(func(m *Machine, req apiRequest) apiResponse {
+		return apiResponse{Text: m.decipherMessage(req.Text)}
+	}))
+	mux.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := s.readRequest(w, r)
+		if !ok {
+			return
+		}
+		valid := true
+		resp := apiResponse{Valid: &valid}
+		if _, err := NewMachineFromKey(req.Key, req.Alphabet); err != nil {
+			valid = false
+			resp.Error = err.Error()
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	mux.HandleFunc("/trace", s.endpoint(func(m *Machine, req apiRequest) apiResponse {
+		r := apiResponse{Trace: []apiTraceStep{}}
+		m.SetTrace(func(e TraceEvent) {
+			path := "twenties"
+			if e.Sixes {
+				path = "sixes"
+			}
+			r.Trace = append(r.Trace, apiTraceStep{
+				Index:  e.Index,
+				Input:  string(e.Input),
+				Plug:   e.Plug,
+				Path:   path,
+				Sixes:  e.State.Sixes + 1,
+				Fast:   e.State.Fast + 1,
+				Middle: e.State.Middle + 1,
+				Slow:   e.State.Slow + 1,
+				Output: string(e.Output),
+			})
+		})
+		if req.Mode == "encipher" {
+			r.Text = m.encipherMessage(req.Text)
+		} else {
+			r.Text = m.decipherMessage(req.Text)
+		}
+		return r
+	}))
+	if s.static != "" {
+		mux.Handle("/", http.FileServer(http.Dir(s.static)))
+	}
+	return mux
+}
+
+// readRequest decodes and size-limits the request, checking its method and mode. If it is not
+// acceptable, readRequest writes the error response and returns false.
+func (s *apiServer) readRequest(w http.ResponseWriter, r *http.Request) (apiRequest, bool) {
+	var req apiRequest
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiResponse{Error: "use POST"})
+		return req, false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiResponse{Error: err.Error()})
+		return req, false
+	}
+	if req.Mode != "" && req.Mode != "encipher" && req.Mode != "decipher" {
+		writeJSON(w, http.StatusBadRequest, apiResponse{Error: fmt.Sprintf("mode %q should be encipher or decipher", req.Mode)})
+		return req, false
+	}
+	return req, true
+}
+
+// endpoint wraps f as an HTTP handler: it reads the request, builds a Machine with
+// NewMachineFromKey and writes f's response as JSON.
+func (s *apiServer) endpoint(f func(m *Machine, req apiRequest) apiResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := s.readRequest(w, r)
+		if !ok {
+			return
+		}
+		m, err := NewMachineFromKey(req.Key, req.Alphabet)
+		if err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, apiResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, f(m, req))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, r apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(r)
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8026", "address to listen on")
+	maxBytes := fs.Int64("max-bytes", 1<<20, "largest request body accepted, in bytes")
+	static := fs.String("static", "", "directory of files to serve as well, such as the web demo")
+	fs.Parse(args)
+	if *maxBytes <= 0 {
+		return fmt.Errorf("max-bytes = %d, must be positive", *maxBytes)
+	}
+
+	s := &apiServer{maxBytes: *maxBytes, static: *static}
+	if *static != "" {
+		log.Printf("serving files from %s", *static)
+	}
+	log.Printf("purple API listening on http://%s/ (encipher, decipher, validate, trace)", *addr)
+	return http.ListenAndServe(*addr, s.handler())
+}
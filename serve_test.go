@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	s := &apiServer{maxBytes: 256}
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	yes, no := true, false
+	var tests = []struct {
+		path   string
+		body   string
+		status int
+		want   apiResponse
+	}{
+		{"/decipher", `{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF","text":"ZTXODNWKCC"}`,
+			http.StatusOK, apiResponse{Text: "FOVTATAKID"}},
+		{"/encipher", `{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF","text":"FOVTATAKID"}`,
+			http.StatusOK, apiResponse{Text: "ZTXODNWKCC"}},
+		{"/validate", `{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF"}`,
+			http.StatusOK, apiResponse{Valid: &yes}},
+		{"/validate", `{"key":"9-1,24,6-11","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF"}`,
+			http.StatusOK, apiResponse{Valid: &no, Error: "fast and middle (1, 1) must be different"}},
+		{"/decipher", `{"key":"9-1,24,6-11","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF"}`,
+			http.StatusUnprocessableEntity, apiResponse{Error: "fast and middle (1, 1) must be different"}},
+		{"/trace", `{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF","text":"ZT","mode":"Encipher"}`,
+			http.StatusBadRequest, apiResponse{Error: `mode "Encipher" should be encipher or decipher`}},
+		{"/decipher", `{"key":`, http.StatusBadRequest, apiResponse{}},
+		{"/decipher", `{"key":"9-1,24,6-23","text":"` + strings.Repeat("A", 300) + `"}`,
+			http.StatusBadRequest, apiResponse{}},
+	}
+	for _, test := range tests {
+		resp, err := http.Post(ts.URL+test.path, "application/json", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("POST %s failed: %s", test.path, err)
+		}
+		var got apiResponse
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("POST %s returned bad JSON: %s", test.path, err)
+			continue
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("POST %s %s returned status %d, want %d", test.path, test.body, resp.StatusCode, test.status)
+		}
+		if test.status != http.StatusOK && got.Error == "" {
+			t.Errorf("POST %s %s returned no error message", test.path, test.body)
+		}
+		if test.want.Error == "" {
+			got.Error = ""
+		}
+		validDiffers := (got.Valid == nil) != (test.want.Valid == nil) || got.Valid != nil && *got.Valid != *test.want.Valid
+		if got.Text != test.want.Text || validDiffers || got.Error != test.want.Error {
+			t.Errorf("POST %s %s returned %+v, want %+v", test.path, test.body, got, test.want)
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/trace", "application/json",
+		strings.NewReader(`{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF","text":"ZT"}`))
+	if err != nil {
+		t.Fatalf("POST /trace failed: %s", err)
+	}
+	defer resp.Body.Close()
+	var got apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("POST /trace returned bad JSON: %s", err)
+	}
+	want := apiTraceStep{Index: 1, Input: "T", Plug: 3, Path: "sixes", Sixes: 10, Fast: 25, Middle: 6, Slow: 1, Output: "O"}
+	if got.Text != "FO" || len(got.Trace) != 2 || got.Trace[1] != want {
+		t.Errorf("POST /trace returned %+v, want text FO and second step %+v", got, want)
+	}
+
+	resp, err = http.Get(ts.URL + "/decipher")
+	if err != nil {
+		t.Fatalf("GET /decipher failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /decipher returned status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeStatic(t *testing.T) {
+	s := &apiServer{maxBytes: 256, static: "web"}
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "purple.newMachine") {
+		t.Errorf("GET / returned status %d and no demo page", resp.StatusCode)
+	}
+	resp, err = http.Post(ts.URL+"/validate", "application/json",
+		strings.NewReader(`{"key":"9-1,24,6-23","alphabet":"NOKTYUXEQLHBRMPDICJASVWGZF"}`))
+	if err != nil {
+		t.Fatalf("POST /validate failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("POST /validate returned status %d alongside static files", resp.StatusCode)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// simulator is an interactive session with one Machine, as if at its keyboard.
+type simulator struct {
+	key      string
+	alphabet string
+	m        *Machine
+	encipher bool
+	history  []State // Machine state before each letter typed since the last reset
+	output   []byte  // Output letters since the last reset
+	out      io.Writer
+}
+
+const simHelp = `Type letters to encipher or decipher them one at a time. Commands:
+  :key K         set the switch key (e.g. 9-1,24,6-23) and reset
+  :alphabet A    set the plugboard alphabet and reset
+  :reset         return the switches to the key positions
+  :back [n]      undo the last n letters (default 1)
+  :jump n        move to n letters after the key positions
+  :encipher      switch to encipher mode
+  :decipher      switch to decipher mode
+  :state         show the switch positions
+  :help          show this help
+  :quit          leave the simulator
+`
+
+func newSimulator(key, alphabet string, out io.Writer) (*simulator, error) {
+	s := &simulator{key: key, alphabet: alphabet, encipher: true, out: out}
+	return s, s.reset()
+}
+
+// reset builds a fresh machine from the current key and alphabet.
+func (s *simulator) reset() error {
+	m, err := NewMachineFromKey(s.key, s.alphabet)
+	if err != nil {
+		return err
+	}
+	s.m = m
+	s.history = s.history[:0]
+	s.output = s.output[:0]
+	return nil
+}
+
+// showState prints the number of letters typed and the positions (1-25) of the switches.
+func (s *simulator) showState() {
+	st := s.m.state()
+	mode := "decipher"
+	if s.encipher {
+		mode = "encipher"
+	}
+	fmt.Fprintf(s.out, "[%s %d] sixes %2d  fast %2d  middle %2d  slow %2d\n", mode, len(s.history),
+		st.Sixes+1, st.Fast+1, st.Middle+1, st.Slow+1)
+}
+
+// letter processes one typed letter and prints the result.
+func (s *simulator) letter(c byte) {
+	s.history = append(s.history, s.m.state())
+	var r string
+	if s.encipher {
+		r = s.m.encipherMessage(string(c))
+	} else {
+		r = s.m.decipherMessage(string(c))
+	}
+	s.output = append(s.output, r[0])
+	fmt.Fprintf(s.out, "%c -> %c  ", c, r[0])
+	s.showState()
+}
+
+// back undoes the last n letters.
+func (s *simulator) back(n int) {
+	if n > len(s.history) {
+		n = len(s.history)
+	}
+	if n <= 0 {
+		return
+	}
+	k := len(s.history) - n
+	s.m.setState(s.history[k])
+	s.history = s.history[:k]
+	s.output = s.output[:k]
+}
+
+// jump moves the machine n letters past the key positions, as if n letters had been typed.
+func (s *simulator) jump(n int) error {
+	if err := s.reset(); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		s.history = append(s.history, s.m.state())
+		s.output = append(s.output, '-')
+		s.m.step()
+	}
+	return nil
+}
+
+// command runs one ':' command line. It returns false when the session should end.
+func (s *simulator) command(line string) (bool, error) {
+	fields := strings.Fields(line)
+	arg := func() (string, error) {
+		if len(fields) != 2 {
+			return "", fmt.Errorf("%s needs one argument", fields[0])
+		}
+		return fields[1], nil
+	}
+	switch fields[0] {
+	case ":key", ":alphabet":
+		a, err := arg()
+		if err != nil {
+			return true, err
+		}
+		oldKey, oldAlphabet := s.key, s.alphabet
+		if fields[0] == ":key" {
+			s.key = a
+		} else {
+			s.alphabet = a
+		}
+		if err := s.reset(); err != nil {
+			s.key, s.alphabet = oldKey, oldAlphabet
+			return true, err
+		}
+	case ":reset":
+		if err := s.reset(); err != nil {
+			return true, err
+		}
+	case ":back":
+		n := 1
+		if len(fields) > 1 {
+			var err error
+			if n, err = strconv.Atoi(fields[1]); err != nil {
+				return true, err
+			}
+		}
+		s.back(n)
+	case ":jump":
+		a, err := arg()
+		if err != nil {
+			return true, err
+		}
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 0 {
+			return true, fmt.Errorf("jump needs a letter count >= 0, got %q", a)
+		}
+		if err := s.jump(n); err != nil {
+			return true, err
+		}
+	case ":encipher":
+		s.encipher = true
+	case ":decipher":
+		s.encipher = false
+	case ":state":
+		fmt.Fprintf(s.out, "key %s  alphabet %s  output %s\n", s.key, s.alphabet, s.output)
+	case ":help":
+		fmt.Fprint(s.out, simHelp)
+		return true, nil
+	case ":quit":
+		return false, nil
+	default:
+		return true, fmt.Errorf("unknown command %s (try :help)", fields[0])
+	}
+	s.showState()
+	return true, nil
+}
+
+// run reads lines from in until EOF or :quit. Letters are processed one at a time; spaces and
+// other characters are ignored.
+func (s *simulator) run(in io.Reader) error {
+	s.showState()
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, ":") {
+			more, err := s.command(line)
+			if err != nil {
+				fmt.Fprintf(s.out, "error: %s\n", err)
+			}
+			if !more {
+				return nil
+			}
+			continue
+		}
+		for _, c := range []byte(line) {
+			if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+				s.letter(c)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func runSimulator(args []string) error {
+	fs := flag.NewFlagSet("sim", flag.ExitOnError)
+	key, alphabet := keyFlags(fs)
+	fs.Parse(args)
+
+	s, err := newSimulator(*key, *alphabet, os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, simHelp)
+	return s.run(os.Stdin)
+}
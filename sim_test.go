@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSimulator(t *testing.T) {
+	var out bytes.Buffer
+	s, err := newSimulator(defaultKey, defaultAlphabet, &out)
+	if err != nil {
+		t.Fatalf("newSimulator failed: %s", err)
+	}
+	script := `:decipher
+ZTXOD NWKCC
+:back 3
+:state
+KCC
+:state
+:jump 2
+:state
+:encipher
+:key 1-2
+:key 1-1,1,1-13
+:quit
+ZZZZ
+`
+	if err := s.run(strings.NewReader(script)); err != nil {
+		t.Fatalf("simulator run failed: %s", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"Z -> F  [decipher 1] sixes 10  fast 25  middle  6  slow  1",
+		"output FOVTATA\n",
+		"output FOVTATAKID\n",
+		"[decipher 2] sixes 11  fast  1  middle  6  slow  1",
+		"output --\n",
+		"error: ",
+		"[encipher 0] sixes  1  fast  1  middle  1  slow  1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("simulator output does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Z -> ") && strings.Count(text, "Z -> ") != 1 {
+		t.Errorf("simulator processed letters after :quit")
+	}
+}
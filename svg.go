@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Layout of the SVG drawing, in pixels. Rows 0-5 carry the sixes and rows 6-25 the twenties,
+// with a gap between them; columns run from the input letters through the plugboard and the
+// switches to the output letters.
+const (
+	svgRow      = 18  // Height of a row
+	svgTop      = 80  // y of row 0
+	svgGap      = 18  // Extra space between the sixes and the twenties
+	svgLetters  = 30  // x of the input letters
+	svgPlugIn   = 50  // x where the plugboard starts
+	svgSwitches = 190 // x where the switches start
+	svgStage    = 150 // Width of each twenties switch
+	svgPlugOut  = svgSwitches + 3*svgStage
+	svgOutput   = svgPlugOut + 140 + 20 // x of the output letters
+	svgWidth    = svgOutput + 30
+	svgHeight   = svgTop + 26*svgRow + svgGap + 20
+)
+
+// svgY returns the y coordinate of row i.
+func svgY(i int) int {
+	if i >= 6 {
+		return svgTop + i*svgRow + svgGap
+	}
+	return svgTop + i*svgRow
+}
+
+// writeSVG draws the plugboard, the sixes switch and the three twenties switches of m, with
+// the wiring at their current positions, as SVG. If in is a letter 'A'-'Z', the path it takes
+// through encipher (or decipher, if encipher is false) is drawn in red. When deciphering the
+// twenties are drawn in the order the letter passes through them, 3 to 1. The machine is not
+// stepped.
+func (m *Machine) writeSVG(w io.Writer, in byte, encipher bool) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="13">`+"\n",
+		svgWidth, svgHeight)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(bw, `<style>.wire{stroke:#bbb;stroke-width:1;fill:none} .path{stroke:red;stroke-width:2.5;fill:none} `+
+		`.box{fill:#f4f0fa;stroke:#536} .hl{fill:red;font-weight:bold}</style>`+"\n")
+
+	mode := "Decipher"
+	if encipher {
+		mode = "Encipher"
+	}
+	title := fmt.Sprintf("%s, sixes at %d", mode, m.sixes.position+1)
+	for i, s := range m.twenties {
+		title += fmt.Sprintf(", twenties %d (%s) at %d", i+1, m.role(s), s.position+1)
+	}
+	fmt.Fprintf(bw, `<text x="%d" y="22">%s</text>`+"\n", svgLetters-10, title)
+
+	// Work out the path: the row of the letter at each column.
+	stages := []*Switch{m.twenties[0], m.twenties[1], m.twenties[2]}
+	if !encipher {
+		stages = []*Switch{m.twenties[2], m.twenties[1], m.twenties[0]}
+	}
+	wiring := func(s *Switch) switchData {
+		if encipher {
+			return s.encipherWiring
+		}
+		return s.decipherWiring
+	}
+	path := -1 // Row entering the switches
+	var rows [4]int
+	out := -1
+	if in >= 'A' && in <= 'Z' {
+		path = int(m.plugboardIn[in-'A'])
+		rows[0] = path
+		if path < 6 {
+			rows[3] = int(wiring(m.sixes)[m.sixes.position][path])
+			rows[1], rows[2] = -1, -1
+		} else {
+			for i, s := range stages {
+				rows[i+1] = 6 + int(wiring(s)[s.position][rows[i]-6])
+			}
+		}
+		out = int(m.plugboardOut[rows[3]])
+	}
+
+	// Letters, and the plugboard on each side.
+	for i := 0; i < 26; i++ {
+		class := ""
+		if path >= 0 && i == int(in-'A') {
+			class = ` class="hl"`
+		}
+		fmt.Fprintf(bw, `<text x="%d" y="%d"%s>%c</text>`+"\n", svgLetters-10, svgY(i)+4, class, 'A'+i)
+		class = ""
+		if i == out {
+			class = ` class="hl"`
+		}
+		fmt.Fprintf(bw, `<text x="%d" y="%d"%s>%c</text>`+"\n", svgOutput, svgY(i)+4, class, 'A'+i)
+	}
+	fmt.Fprintf(bw, `<text x="%d" y="%d">plugboard</text>`+"\n", svgPlugIn+30, svgTop-20)
+	fmt.Fprintf(bw, `<text x="%d" y="%d">plugboard</text>`+"\n", svgPlugOut+40, svgTop-20)
+	for i := 0; i < 26; i++ {
+		bw.WriteString(svgWire(svgPlugIn, svgY(i), svgSwitches, svgY(int(m.plugboardIn[i])), "wire"))
+		bw.WriteString(svgWire(svgPlugOut, svgY(i), svgOutput-10, svgY(int(m.plugboardOut[i])), "wire"))
+	}
+
+	// The sixes switch spans all three twenties stages.
+	svgBox(bw, svgSwitches, svgY(0)-svgRow/2, 3*svgStage, 6*svgRow,
+		fmt.Sprintf("sixes at %d", m.sixes.position+1))
+	for i := 0; i < 6; i++ {
+		bw.WriteString(svgWire(svgSwitches, svgY(i), svgPlugOut, svgY(int(wiring(m.sixes)[m.sixes.position][i])), "wire"))
+	}
+	for k, s := range stages {
+		x := svgSwitches + k*svgStage
+		n := 1
+		for i, t := range m.twenties {
+			if t == s {
+				n = i + 1
+			}
+		}
+		svgBox(bw, x+4, svgY(6)-svgRow/2, svgStage-8, 20*svgRow,
+			fmt.Sprintf("twenties %d %s at %d", n, m.role(s), s.position+1))
+		for i := 0; i < 20; i++ {
+			bw.WriteString(svgWire(x, svgY(6+i), x+svgStage, svgY(6+int(wiring(s)[s.position][i])), "wire"))
+		}
+	}
+
+	// The path of the letter, over everything else.
+	if path >= 0 {
+		bw.WriteString(svgWire(svgPlugIn, svgY(int(in-'A')), svgSwitches, svgY(rows[0]), "path"))
+		if path < 6 {
+			bw.WriteString(svgWire(svgSwitches, svgY(rows[0]), svgPlugOut, svgY(rows[3]), "path"))
+		} else {
+			for k := 0; k < 3; k++ {
+				x := svgSwitches + k*svgStage
+				bw.WriteString(svgWire(x, svgY(rows[k]), x+svgStage, svgY(rows[k+1]), "path"))
+			}
+		}
+		bw.WriteString(svgWire(svgPlugOut, svgY(rows[3]), svgOutput-10, svgY(out), "path"))
+		fmt.Fprintf(bw, `<text x="%d" y="44" font-size="15" class="hl">%c → %c</text>`+"\n",
+			svgLetters-10, in, 'A'+out)
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// svgWire returns an S-shaped wire from (x1, y1) to (x2, y2).
+func svgWire(x1, y1, x2, y2 int, class string) string {
+	mid := (x1 + x2) / 2
+	return fmt.Sprintf(`<path class="%s" d="M%d,%d C%d,%d %d,%d %d,%d"/>`+"\n", class, x1, y1, mid, y1, mid, y2, x2, y2)
+}
+
+// svgBox draws a labelled switch box.
+func svgBox(w io.Writer, x, y, width, height int, label string) {
+	fmt.Fprintf(w, `<rect class="box" x="%d" y="%d" width="%d" height="%d" rx="4"/>`+"\n", x, y, width, height)
+	fmt.Fprintf(w, `<text x="%d" y="%d" font-size="11" fill="#536">%s</text>`+"\n", x+4, y-3, label)
+}
+
+func runSVG(args []string) error {
+	fs := flag.NewFlagSet("svg", flag.ExitOnError)
+	newMachine := machineFlags(fs)
+	decipher := fs.Bool("decipher", false, "draw the paths of letters being deciphered (default: enciphered)")
+	prefix := fs.String("o", "", "write one SVG per letter of the text to <prefix>-001.svg and on, for animation")
+	fs.Parse(args)
+
+	m, err := newMachine()
+	if err != nil {
+		return err
+	}
+	if *prefix == "" {
+		// One drawing of the machine at its key, with the path of the first letter if any.
+		var in byte
+		if fs.NArg() > 0 && fs.Arg(0) != "" {
+			in = fs.Arg(0)[0]
+			if in >= 'a' && in <= 'z' {
+				in -= 'a' - 'A'
+			}
+		}
+		return m.writeSVG(os.Stdout, in, !*decipher)
+	}
+
+	text, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+	frame := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c >= 'A' && c <= 'Z' {
+			frame++
+			f, err := os.Create(fmt.Sprintf("%s-%03d.svg", *prefix, frame))
+			if err != nil {
+				return err
+			}
+			err = m.writeSVG(f, c, !*decipher)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+		// Step exactly as encipherMessage and decipherMessage do.
+		if *decipher {
+			m.decipherMessage(text[i : i+1])
+		} else {
+			m.encipherMessage(text[i : i+1])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d frames, %s-001.svg to %s-%03d.svg\n", frame, *prefix, *prefix, frame)
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// svgHighlighted returns the text of every element of class "hl" in svg, checking that svg
+// is well-formed XML.
+func svgHighlighted(t *testing.T, svg []byte) []string {
+	var hl []string
+	d := xml.NewDecoder(bytes.NewReader(svg))
+	inHL := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return hl
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed: %s", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			inHL = false
+			for _, a := range tok.Attr {
+				if a.Name.Local == "class" && a.Value == "hl" && tok.Name.Local == "text" {
+					inHL = true
+				}
+			}
+		case xml.CharData:
+			if inHL {
+				hl = append(hl, string(tok))
+			}
+		case xml.EndElement:
+			inHL = false
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	start := m.state()
+	for _, encipher := range []bool{true, false} {
+		for _, in := range []byte("ANZ") { // N goes through the sixes with this alphabet, A and Z the twenties
+			var buf bytes.Buffer
+			if err := m.writeSVG(&buf, in, encipher); err != nil {
+				t.Fatalf("writeSVG failed: %s", err)
+			}
+			out := m.encipher(in-'A') + 'A'
+			if !encipher {
+				out = m.decipher(in-'A') + 'A'
+			}
+			want := []string{string(in), string(out), string(in) + " → " + string(out)}
+			got := svgHighlighted(t, buf.Bytes())
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("writeSVG(%c, encipher=%v) highlighted %q, want %q", in, encipher, got, want)
+			}
+			if n := strings.Count(buf.String(), `class="path"`); n != 3 && n != 5 {
+				t.Errorf("writeSVG(%c) drew %d path segments, want 3 (sixes) or 5 (twenties)", in, n)
+			}
+			if !strings.Contains(buf.String(), "sixes at 9, twenties 1 (slow) at 1, twenties 2 (fast) at 24, twenties 3 (middle) at 6") {
+				t.Errorf("writeSVG title does not give the switch positions")
+			}
+		}
+	}
+	if m.state() != start {
+		t.Errorf("writeSVG stepped the machine")
+	}
+
+	var buf bytes.Buffer
+	m.writeSVG(&buf, 0, true)
+	if got := svgHighlighted(t, buf.Bytes()); len(got) != 0 || strings.Contains(buf.String(), `class="path"`) {
+		t.Errorf("writeSVG with no letter highlighted %q", got)
+	}
+}
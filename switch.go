@@ -38,6 +38,12 @@ func (s *Switch) setPosition(i int) int {
 	return s.position
 }
 
+// clone returns a new Switch at the same position, sharing (read-only) wiring with s.
+func (s *Switch) clone() *Switch {
+	c := *s
+	return &c
+}
+
 func (s *Switch) encipher(p byte) byte {
 	return s.encipherWiring[s.position][p]
 }
@@ -0,0 +1,163 @@
+package main
+
+import "sync"
+
+// tableStates is the number of combined positions of the sixes and three twenties switches.
+const tableStates = 25 * 25 * 25 * 25
+
+// machineTable holds, for one plugboard alphabet, the complete 26-letter encipher and decipher
+// permutations at every combined position of the four switches. It does not depend on the
+// starting positions or on which twenties switch is fast, middle or slow, so one table serves
+// every key with the same alphabet. Each direction takes about 10 MB.
+type machineTable struct {
+	alphabet string
+	encipher [][26]byte // Indexed by tableIndex
+	decipher [][26]byte
+}
+
+var (
+	tableCacheLock sync.Mutex
+	tableCache     *machineTable // The table last returned by cachedMachineTable
+)
+
+// cachedMachineTable returns the machineTable for the alphabet of m. Only the last table is
+// kept, so a new alphabet costs a fraction of a second to build its table, and memory stays
+// at one table however many alphabets are tried.
+func cachedMachineTable(m *Machine) *machineTable {
+	tableCacheLock.Lock()
+	defer tableCacheLock.Unlock()
+	if tableCache == nil || tableCache.alphabet != m.alphabet {
+		tableCache = newMachineTable(m)
+	}
+	return tableCache
+}
+
+// positionIndex numbers a combination of sixes and twenties #1, #2 and #3 positions (0-24)
+// from 0 to tableStates-1.
+func positionIndex(sixes, tw1, tw2, tw3 int) int {
+	return ((sixes*25+tw1)*25+tw2)*25 + tw3
+}
+
+// tableIndex returns the index of m's current switch positions in a machineTable.
+func (m *Machine) tableIndex() int {
+	return positionIndex(m.sixes.position, m.twenties[0].position, m.twenties[1].position, m.twenties[2].position)
+}
+
+// newMachineTable builds the tables for the alphabet of m. The state of m is unchanged. Use
+// cachedMachineTable rather than building a table for an alphabet twice.
+func newMachineTable(m *Machine) *machineTable {
+	t := &machineTable{
+		alphabet: m.alphabet,
+		encipher: make([][26]byte, tableStates),
+		decipher: make([][26]byte, tableStates),
+	}
+	w := m.clone()
+	i := 0
+	for s := 0; s < 25; s++ {
+		w.sixes.setPosition(s)
+		for t0 := 0; t0 < 25; t0++ {
+			w.twenties[0].setPosition(t0)
+			for t1 := 0; t1 < 25; t1++ {
+				w.twenties[1].setPosition(t1)
+				for t2 := 0; t2 < 25; t2++ {
+					w.twenties[2].setPosition(t2)
+					for c := byte(0); c < 26; c++ {
+						t.encipher[i][c] = w.encipher(c)
+						t.decipher[i][c] = w.decipher(c)
+					}
+					i++
+				}
+			}
+		}
+	}
+	return t
+}
+
+// decipherMessage works exactly like m.decipherMessage, stepping m, but looks up each letter in
+// the table. The machine m must have the alphabet the table was built for.
+func (t *machineTable) decipherMessage(m *Machine, cipher string) string {
+	result := []byte(cipher)
+	t.DecipherBytes(m, result, result)
+	return string(result)
+}
+
+// encipherMessage works exactly like m.encipherMessage, stepping m, but looks up each letter in
+// the table. The machine m must have the alphabet the table was built for.
+func (t *machineTable) encipherMessage(m *Machine, plain string) string {
+	result := []byte(plain)
+	t.EncipherBytes(m, result, result)
+	return string(result)
+}
+
+// DecipherBytes works exactly like m.DecipherBytes, stepping m and writing to dst, which may be
+// src itself, without allocating.
+func (t *machineTable) DecipherBytes(m *Machine, dst, src []byte) {
+	t.transform(m, dst, src, t.decipher)
+}
+
+// EncipherBytes works exactly like m.EncipherBytes, as DecipherBytes does for deciphering.
+func (t *machineTable) EncipherBytes(m *Machine, dst, src []byte) {
+	t.transform(m, dst, src, t.encipher)
+}
+
+// transform looks each letter of src up in perms. It steps local copies of the switch
+// positions by the rules of Machine.step, moving the table index with them, and sets the
+// switches of m only at the end, so each letter costs a single table lookup.
+func (t *machineTable) transform(m *Machine, dst, src []byte, perms [][26]byte) {
+	if m.alphabet != t.alphabet {
+		panic("purple: machineTable used with a machine of a different alphabet")
+	}
+	if len(dst) < len(src) {
+		panic("purple: machineTable output smaller than input")
+	}
+	// pos and mult hold the position and index weight of the sixes and twenties #1, #2 and #3.
+	var pos, mult [4]int
+	var fast, middle, slow int
+	pos[0], mult[0] = m.sixes.position, positionIndex(1, 0, 0, 0)
+	for k, tw := range m.twenties {
+		pos[k+1] = tw.position
+		switch tw {
+		case m.fast:
+			fast = k + 1
+		case m.middle:
+			middle = k + 1
+		default:
+			slow = k + 1
+		}
+	}
+	mult[1], mult[2], mult[3] = positionIndex(0, 1, 0, 0), positionIndex(0, 0, 1, 0), positionIndex(0, 0, 0, 1)
+	i := positionIndex(pos[0], pos[1], pos[2], pos[3])
+	advance := func(s int) {
+		if pos[s] == 24 {
+			pos[s] = 0
+			i -= 24 * mult[s]
+		} else {
+			pos[s]++
+			i += mult[s]
+		}
+	}
+	for k, c := range src {
+		if c >= 'A' && c <= 'Z' {
+			dst[k] = perms[i][c-'A'] + 'A'
+		} else if c >= 'a' && c <= 'z' {
+			dst[k] = perms[i][c-'a'] + 'a'
+		} else {
+			dst[k] = c
+		}
+		if c == ' ' || c == '\n' {
+			continue
+		}
+		if pos[middle] == 24 && pos[0] == 23 {
+			advance(slow)
+		} else if pos[0] == 24 {
+			advance(middle)
+		} else {
+			advance(fast)
+		}
+		advance(0)
+	}
+	m.sixes.setPosition(pos[0])
+	for k, tw := range m.twenties {
+		tw.setPosition(pos[k+1])
+	}
+}
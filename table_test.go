@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMachineTable(t *testing.T) {
+	text := strings.Repeat(englishSample+"\n"+strings.ToLower(englishSample)+" -", 20)
+	for _, test := range []struct{ key, alphabet string }{
+		{"9-1,24,6-23", defaultAlphabet},
+		{"1-1,1,1-13", defaultAlphabet},
+		{"25-25,25,25-31", "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+		{"5-20,7,18-21", "QWERTYUIOPASDFGHJKLZXCVBNM"},
+		{"12-3,17,9-32", "QWERTYUIOPASDFGHJKLZXCVBNM"},
+	} {
+		key := test.key
+		m1, _ := NewMachineFromKey(key, test.alphabet)
+		m2, _ := NewMachineFromKey(key, test.alphabet)
+		table := cachedMachineTable(m1)
+		if cachedMachineTable(m2) != table {
+			t.Errorf("cachedMachineTable built a second table for alphabet %s", test.alphabet)
+		}
+		want := m1.encipherMessage(text)
+		if got := table.encipherMessage(m2, text); got != want {
+			t.Errorf("machineTable.encipherMessage differs from Machine.encipherMessage for key %s", key)
+		}
+		if m1.state() != m2.state() {
+			t.Errorf("machineTable left machine in state %v, want %v", m2.state(), m1.state())
+		}
+		m1, _ = NewMachineFromKey(key, test.alphabet)
+		m2, _ = NewMachineFromKey(key, test.alphabet)
+		cipher := want
+		want = m1.decipherMessage(cipher)
+		if got := table.decipherMessage(m2, cipher); got != want {
+			t.Errorf("machineTable.decipherMessage differs from Machine.decipherMessage for key %s", key)
+		}
+		if m1.state() != m2.state() {
+			t.Errorf("machineTable left machine in state %v, want %v", m2.state(), m1.state())
+		}
+	}
+}
+
+// benchmarkCipher returns a machine at the 14-part key and a long ciphertext.
+func benchmarkCipher(b *testing.B) (*Machine, string) {
+	m, err := NewMachineFromKey(defaultKey, defaultAlphabet)
+	if err != nil {
+		b.Fatalf("Could not make machine: %s", err)
+	}
+	cipher := m.encipherMessage(strings.Repeat(englishSample, 10))
+	m.setState(State{8, 23, 5, 0})
+	return m, cipher
+}
+
+func BenchmarkTableDecipherBytes(b *testing.B) {
+	m, cipher := benchmarkCipher(b)
+	table := cachedMachineTable(m)
+	src := []byte(cipher)
+	dst := make([]byte, len(src))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.DecipherBytes(m, dst, src)
+	}
+}
+
+func BenchmarkNewMachineTable(b *testing.B) {
+	m, _ := benchmarkCipher(b)
+	for i := 0; i < b.N; i++ {
+		newMachineTable(m)
+	}
+}
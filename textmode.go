@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TextMode selects how EncipherText and DecipherText treat case and characters other than
+// letters.
+type TextMode int
+
+const (
+	// ModePreserve keeps the case of letters and passes other characters through unchanged,
+	// stepping the machine for all but spaces and newlines, as decipherMessage does.
+	ModePreserve TextMode = iota
+	// ModeUpper converts lower-case letters to capitals, and is otherwise ModePreserve.
+	ModeUpper
+	// ModeStrip removes everything but letters before processing.
+	ModeStrip
+	// ModeStrict rejects text holding anything but letters.
+	ModeStrict
+)
+
+var textModeNames = []string{"preserve", "upper", "strip", "strict"}
+
+func (mode TextMode) String() string {
+	if mode < 0 || int(mode) >= len(textModeNames) {
+		return fmt.Sprintf("TextMode(%d)", int(mode))
+	}
+	return textModeNames[mode]
+}
+
+// ParseTextMode returns the mode with the given name: preserve, upper, strip or strict.
+func ParseTextMode(name string) (TextMode, error) {
+	for i, n := range textModeNames {
+		if n == name {
+			return TextMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("text mode %q should be preserve, upper, strip or strict", name)
+}
+
+// prepare applies mode to text before it goes through the machine.
+func (mode TextMode) prepare(text string) (string, error) {
+	switch mode {
+	case ModePreserve:
+		return text, nil
+	case ModeUpper:
+		b := []byte(text)
+		for i, c := range b {
+			if c >= 'a' && c <= 'z' {
+				b[i] = c - 'a' + 'A'
+			}
+		}
+		return string(b), nil
+	case ModeStrip:
+		var b bytes.Buffer
+		for i := 0; i < len(text); i++ {
+			if c := text[i]; (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+				b.WriteByte(c)
+			}
+		}
+		return b.String(), nil
+	case ModeStrict:
+		for i := 0; i < len(text); i++ {
+			if c := text[i]; !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+				return "", fmt.Errorf("character %q at offset %d is not a letter", c, i)
+			}
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("unknown %s", mode)
+}
+
+// EncipherText enciphers text after applying mode to it. In strict mode the machine is not
+// stepped if the text is rejected.
+func (m *Machine) EncipherText(text string, mode TextMode) (string, error) {
+	text, err := mode.prepare(text)
+	if err != nil {
+		return "", err
+	}
+	return m.encipherMessage(text), nil
+}
+
+// DecipherText deciphers text after applying mode to it, as EncipherText does.
+func (m *Machine) DecipherText(text string, mode TextMode) (string, error) {
+	text, err := mode.prepare(text)
+	if err != nil {
+		return "", err
+	}
+	return m.decipherMessage(text), nil
+}
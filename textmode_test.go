@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestTextMode(t *testing.T) {
+	const text = "Attack at dawn, 0600.\n"
+	var tests = []struct {
+		mode TextMode
+		want string // Deciphered text, or "" if text is rejected
+	}{
+		{ModePreserve, "Attack at dawn, 0600.\n"},
+		{ModeUpper, "ATTACK AT DAWN, 0600.\n"},
+		{ModeStrip, "Attackatdawn"},
+		{ModeStrict, ""},
+	}
+	for _, test := range tests {
+		m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+		start := m.state()
+		cipher, err := m.EncipherText(text, test.mode)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%s: EncipherText(%q) should raise error", test.mode, text)
+			}
+			if m.state() != start {
+				t.Errorf("%s: rejected text stepped the machine", test.mode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: EncipherText failed: %s", test.mode, err)
+			continue
+		}
+		if len(cipher) != len(test.want) {
+			t.Errorf("%s: EncipherText gave %q, want %d characters", test.mode, cipher, len(test.want))
+		}
+		m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+		if plain, err := m.DecipherText(cipher, test.mode); err != nil || plain != test.want {
+			t.Errorf("%s: DecipherText gave %q, %v, want %q", test.mode, plain, err, test.want)
+		}
+	}
+
+	// Each mode agrees with decipherMessage on text that is all capitals.
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	want := m.decipherMessage(englishSample)
+	for mode := ModePreserve; mode <= ModeStrict; mode++ {
+		m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+		if got, err := m.DecipherText(englishSample, mode); err != nil || got != want {
+			t.Errorf("%s: DecipherText of capitals differs from decipherMessage", mode)
+		}
+	}
+
+	// Strict mode accepts lower case; strip keeps it.
+	m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+	if got, err := m.EncipherText("attack", ModeStrict); err != nil || got != "fnkijy" {
+		t.Errorf("strict: EncipherText(\"attack\") = %q, %v", got, err)
+	}
+
+	for _, name := range []string{"preserve", "upper", "strip", "strict"} {
+		if mode, err := ParseTextMode(name); err != nil || mode.String() != name {
+			t.Errorf("ParseTextMode(%q) = %v, %v", name, mode, err)
+		}
+	}
+	if _, err := ParseTextMode("lower"); err == nil {
+		t.Errorf("ParseTextMode(\"lower\") should raise error")
+	}
+	if _, err := TextMode(9).prepare("A"); err == nil {
+		t.Errorf("unknown text mode should raise error")
+	}
+}
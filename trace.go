@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// TraceEvent describes the passage of one letter through the machine.
+type TraceEvent struct {
+	Index    int   // Index of the letter in the message
+	Encipher bool  // True when enciphering, false when deciphering
+	Input    byte  // Input letter, 'A'-'Z'
+	Plug     int   // Plugboard output (0-25) for the input letter; 0-5 go to the sixes
+	Sixes    bool  // True if the letter went through the sixes switch, false for the twenties
+	State    State // Switch positions used for the letter, before the machine steps
+	Output   byte  // Output letter, 'A'-'Z'
+}
+
+// SetTrace installs f to be called for every letter enciphered or deciphered by
+// encipherMessage and decipherMessage. A nil f turns tracing off.
+func (m *Machine) SetTrace(f func(TraceEvent)) {
+	m.trace = f
+}
+
+// traceLetter reports input letter in (upper or lower case) becoming out at message index i.
+// Non-letters are not reported.
+func (m *Machine) traceLetter(i int, encipher bool, in, out byte) {
+	if in >= 'a' && in <= 'z' {
+		in -= 'a' - 'A'
+		out -= 'a' - 'A'
+	}
+	if in < 'A' || in > 'Z' {
+		return
+	}
+	plug := int(m.plugboardIn[in-'A'])
+	m.trace(TraceEvent{
+		Index:    i,
+		Encipher: encipher,
+		Input:    in,
+		Plug:     plug,
+		Sixes:    plug < 6,
+		State:    m.state(),
+		Output:   out,
+	})
+}
+
+// traceHeader names the columns written by writeTrace. Switch positions are 1-25, as in keys.
+var traceHeader = []string{"index", "mode", "in", "plug", "path", "sixes", "fast", "middle", "slow", "out"}
+
+// traceRecord formats e as one row of writeTrace output.
+func traceRecord(e TraceEvent) []string {
+	mode, path := "D", "twenties"
+	if e.Encipher {
+		mode = "E"
+	}
+	if e.Sixes {
+		path = "sixes"
+	}
+	return []string{
+		strconv.Itoa(e.Index),
+		mode,
+		string(e.Input),
+		strconv.Itoa(e.Plug),
+		path,
+		strconv.Itoa(e.State.Sixes + 1),
+		strconv.Itoa(e.State.Fast + 1),
+		strconv.Itoa(e.State.Middle + 1),
+		strconv.Itoa(e.State.Slow + 1),
+		string(e.Output),
+	}
+}
+
+// checkTraceFormat returns an error unless format is one writeTrace knows.
+func checkTraceFormat(format string) error {
+	if format != "table" && format != "csv" {
+		return fmt.Errorf("trace format %q should be table or csv", format)
+	}
+	return nil
+}
+
+// writeTrace writes events to w in the given format, which must be "table" or "csv".
+func writeTrace(w io.Writer, format string, events []TraceEvent) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+		writeRow := func(row []string) {
+			for _, field := range row {
+				fmt.Fprintf(tw, "%s\t", field)
+			}
+			fmt.Fprintln(tw)
+		}
+		writeRow(traceHeader)
+		for _, e := range events {
+			writeRow(traceRecord(e))
+		}
+		return tw.Flush()
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(traceHeader)
+		for _, e := range events {
+			cw.Write(traceRecord(e))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return checkTraceFormat(format)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	m, err := NewMachine(21, 1, 25, 5, 1, 2, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	if err != nil {
+		t.Fatalf("Could not complete NewMachine: %s", err.Error())
+	}
+	var events []TraceEvent
+	m.SetTrace(func(e TraceEvent) {
+		events = append(events, e)
+	})
+	plain := "Ab z-C"
+	cipher := m.encipherMessage(plain)
+
+	// Letters and the garble are stepped over, but only letters are traced.
+	wantIndex := []int{0, 1, 3, 5}
+	if len(events) != len(wantIndex) {
+		t.Fatalf("Traced %d letters, want %d", len(events), len(wantIndex))
+	}
+	wantSixes := []int{20, 21, 22, 24}
+	for i, e := range events {
+		if e.Index != wantIndex[i] || !e.Encipher {
+			t.Errorf("event %d has index %d, encipher %v, want %d, true", i, e.Index, e.Encipher, wantIndex[i])
+		}
+		if e.Input != plain[e.Index]&^0x20 || e.Output != cipher[e.Index]&^0x20 {
+			t.Errorf("event %d is %c->%c, want %c->%c", i, e.Input, e.Output, plain[e.Index], cipher[e.Index])
+		}
+		if e.Sixes != (e.Plug < 6) || e.Sixes != (e.Input <= 'F') {
+			t.Errorf("event %d for %c has plug %d, sixes %v", i, e.Input, e.Plug, e.Sixes)
+		}
+		if e.State.Sixes != wantSixes[i] {
+			t.Errorf("event %d has sixes at %d, want %d", i, e.State.Sixes, wantSixes[i])
+		}
+	}
+
+	m.SetTrace(nil)
+	m.decipherMessage("ABC")
+	if len(events) != len(wantIndex) {
+		t.Errorf("SetTrace(nil) did not stop tracing")
+	}
+
+	var buf bytes.Buffer
+	if err := writeTrace(&buf, "csv", events[:1]); err != nil {
+		t.Fatalf("writeTrace failed: %s", err)
+	}
+	want := "index,mode,in,plug,path,sixes,fast,middle,slow,out\n" +
+		"0,E,A,0,sixes,21,1,25,5," + string(events[0].Output) + "\n"
+	if buf.String() != want {
+		t.Errorf("writeTrace(csv) = %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	if err := writeTrace(&buf, "table", events); err != nil {
+		t.Fatalf("writeTrace failed: %s", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 1+len(events) {
+		t.Errorf("writeTrace(table) wrote %d lines, want %d", lines, 1+len(events))
+	}
+	if err := writeTrace(&buf, "xml", events); err == nil {
+		t.Errorf("writeTrace with unknown format should raise error")
+	}
+	if checkTraceFormat("csv") != nil || checkTraceFormat("xml") == nil {
+		t.Errorf("checkTraceFormat should accept csv and reject xml")
+	}
+}
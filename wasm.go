@@ -0,0 +1,125 @@
+//go:build js && wasm
+// +build js,wasm
+
+package main
+
+import "syscall/js"
+
+func init() {
+	jsMain = runJS
+}
+
+// runJS makes the emulator available to JavaScript as the global object purple, then waits
+// for calls from JavaScript for as long as the page is open.
+func runJS() {
+	js.Global().Set("purple", jsAPI())
+	select {}
+}
+
+// jsError returns a JavaScript Error. Go functions called from JavaScript cannot throw, so
+// they return one instead and callers check with instanceof Error.
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
+
+// jsAPI returns the purple object:
+//
+//	purple.defaultKey, purple.defaultAlphabet   settings of part 1 of the 14-part message
+//	purple.newMachine(key, alphabet)            a machine object, or an Error
+func jsAPI() js.Value {
+	api := js.Global().Get("Object").New()
+	api.Set("defaultKey", defaultKey)
+	api.Set("defaultAlphabet", defaultAlphabet)
+	api.Set("newMachine", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 2 {
+			return jsError("newMachine(key, alphabet) takes 2 arguments")
+		}
+		m, err := NewMachineFromKey(args[0].String(), args[1].String())
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return jsMachine(m)
+	}))
+	return api
+}
+
+// jsMachine wraps m as a JavaScript object with methods:
+//
+//	encipher(text[, mode]), decipher(text[, mode])   text, or an Error; mode is a TextMode name
+//	step([n])                                        step n times (default 1) without a letter
+//	state()                                          {sixes, fast, middle, slow}, positions 1-25
+//	reset()                                          return to the starting positions
+//	release()                                        free the Go functions; the object is then dead
+func jsMachine(m *Machine) js.Value {
+	start := m.state()
+	obj := js.Global().Get("Object").New()
+	var funcs []js.Func
+	method := func(name string, f func(args []js.Value) interface{}) {
+		fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return f(args)
+		})
+		funcs = append(funcs, fn)
+		obj.Set(name, fn)
+	}
+	cipher := func(encipher bool) func(args []js.Value) interface{} {
+		return func(args []js.Value) interface{} {
+			if len(args) < 1 || args[0].Type() != js.TypeString {
+				return jsError("expected the text to process")
+			}
+			mode := ModePreserve
+			if len(args) > 1 {
+				var err error
+				if mode, err = ParseTextMode(args[1].String()); err != nil {
+					return jsError(err.Error())
+				}
+			}
+			var text string
+			var err error
+			if encipher {
+				text, err = m.EncipherText(args[0].String(), mode)
+			} else {
+				text, err = m.DecipherText(args[0].String(), mode)
+			}
+			if err != nil {
+				return jsError(err.Error())
+			}
+			return text
+		}
+	}
+
+	method("encipher", cipher(true))
+	method("decipher", cipher(false))
+	method("step", func(args []js.Value) interface{} {
+		n := 1
+		if len(args) > 0 {
+			if args[0].Type() != js.TypeNumber {
+				return jsError("step([n]) takes a number")
+			}
+			n = args[0].Int()
+		}
+		for i := 0; i < n; i++ {
+			m.step()
+		}
+		return nil
+	})
+	method("state", func(args []js.Value) interface{} {
+		st := m.state()
+		return map[string]interface{}{
+			"sixes":  st.Sixes + 1,
+			"fast":   st.Fast + 1,
+			"middle": st.Middle + 1,
+			"slow":   st.Slow + 1,
+		}
+	})
+	method("reset", func(args []js.Value) interface{} {
+		m.setState(start)
+		return nil
+	})
+	method("release", func(args []js.Value) interface{} {
+		for _, fn := range funcs {
+			fn.Release()
+		}
+		return nil
+	})
+	return obj
+}
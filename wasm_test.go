@@ -0,0 +1,54 @@
+//go:build js && wasm
+// +build js,wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// Run with: GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" -run JS
+func TestJSAPI(t *testing.T) {
+	api := jsAPI()
+	m := api.Call("newMachine", api.Get("defaultKey"), api.Get("defaultAlphabet"))
+	errorType := js.Global().Get("Error")
+	if m.InstanceOf(errorType) {
+		t.Fatalf("newMachine failed: %s", m.Get("message"))
+	}
+	defer m.Call("release")
+
+	if got := m.Call("decipher", "ZTXODNWKCC").String(); got != "FOVTATAKID" {
+		t.Errorf("decipher gave %q, want FOVTATAKID", got)
+	}
+	st := m.Call("state")
+	if st.Get("sixes").Int() != 19 || st.Get("fast").Int() != 9 || st.Get("middle").Int() != 6 || st.Get("slow").Int() != 1 {
+		t.Errorf("state after 10 letters is sixes %d, fast %d, middle %d, slow %d",
+			st.Get("sixes").Int(), st.Get("fast").Int(), st.Get("middle").Int(), st.Get("slow").Int())
+	}
+	m.Call("reset")
+	if got := m.Call("encipher", "fovtatakid", "upper").String(); got != "ZTXODNWKCC" {
+		t.Errorf("encipher in upper mode gave %q, want ZTXODNWKCC", got)
+	}
+	m.Call("reset")
+	m.Call("step", 3)
+	if sixes := m.Call("state").Get("sixes").Int(); sixes != 12 {
+		t.Errorf("sixes at %d after 3 steps, want 12", sixes)
+	}
+
+	if e := m.Call("encipher", "NOT LETTERS", "strict"); !e.InstanceOf(errorType) {
+		t.Errorf("encipher in strict mode accepted a space")
+	}
+	if e := m.Call("encipher", "A", "lower"); !e.InstanceOf(errorType) {
+		t.Errorf("encipher accepted an unknown mode")
+	}
+	if e := m.Call("step", "3"); !e.InstanceOf(errorType) {
+		t.Errorf("step accepted a string")
+	}
+	if sixes := m.Call("state").Get("sixes").Int(); sixes != 12 {
+		t.Errorf("sixes at %d after a bad step, want 12", sixes)
+	}
+	if e := api.Call("newMachine", "9-1,24,6-11", defaultAlphabet); !e.InstanceOf(errorType) {
+		t.Errorf("newMachine accepted a bad key")
+	}
+}
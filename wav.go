@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// morseTone describes how Morse is keyed as audio.
+type morseTone struct {
+	Rate  int     // Samples per second
+	WPM   float64 // Words per minute, by the PARIS standard of 50 dot units a word
+	Freq  float64 // Tone frequency in Hz
+	Noise float64 // Standard deviation of white noise, relative to the tone amplitude
+}
+
+// toneAmplitude is the peak tone level as a fraction of full scale, leaving headroom for noise.
+const toneAmplitude = 0.5
+
+// samples renders Morse text, as produced by morseEncode, as audio samples in [-1, 1]. A dot
+// is one unit long, a dash three; elements are separated by one unit of silence, letters by
+// three and words (" / ") by seven. Each tone is shaped with 5ms ramps to avoid key clicks.
+// Noise, if any, is drawn from rng.
+func (t morseTone) samples(morse string, rng *rand.Rand) []float64 {
+	unit := int(float64(t.Rate) * 1.2 / t.WPM)
+	ramp := t.Rate / 200
+	if ramp > unit/2 {
+		ramp = unit / 2
+	}
+	var out []float64
+	key := func(units int, on bool) {
+		n := units * unit
+		for i := 0; i < n; i++ {
+			v := 0.0
+			if on {
+				env := 1.0
+				if i < ramp {
+					env = float64(i) / float64(ramp)
+				} else if n-i < ramp {
+					env = float64(n-i) / float64(ramp)
+				}
+				v = env * math.Sin(2*math.Pi*t.Freq*float64(len(out))/float64(t.Rate))
+			}
+			out = append(out, v)
+		}
+	}
+
+	key(7, false)
+	for w, word := range strings.Split(morse, "/") {
+		if w > 0 {
+			key(7, false)
+		}
+		for l, letter := range strings.Fields(word) {
+			if l > 0 {
+				key(3, false)
+			}
+			for e, element := range letter {
+				if e > 0 {
+					key(1, false)
+				}
+				if element == '-' {
+					key(3, true)
+				} else {
+					key(1, true)
+				}
+			}
+		}
+	}
+	key(7, false)
+
+	for i := range out {
+		v := out[i]
+		if t.Noise > 0 {
+			v += t.Noise * rng.NormFloat64()
+		}
+		out[i] = math.Max(-1, math.Min(1, toneAmplitude*v))
+	}
+	return out
+}
+
+// writeWAV writes samples in [-1, 1] as a mono 16-bit PCM WAV file.
+func writeWAV(w io.Writer, samples []float64, rate int) error {
+	bw := bufio.NewWriter(w)
+	size := 2 * len(samples)
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, uint32(36 + size), [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16),
+		uint16(1), uint16(1), uint32(rate), uint32(2 * rate), uint16(2), uint16(16),
+		[4]byte{'d', 'a', 't', 'a'}, uint32(size),
+	}
+	for _, field := range header {
+		binary.Write(bw, binary.LittleEndian, field)
+	}
+	for _, s := range samples {
+		binary.Write(bw, binary.LittleEndian, int16(math.Floor(s*32767+0.5)))
+	}
+	return bw.Flush()
+}
+
+// readWAV reads a PCM WAV file of 8 or 16 bits a sample. Only the first channel is returned,
+// as samples in [-1, 1], along with the sample rate.
+func readWAV(r io.Reader) ([]float64, int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("not a WAV file")
+	}
+	var channels, bits int
+	var rate int
+	for p := 12; p+8 <= len(data); {
+		id := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		body := data[p+8:]
+		if size > len(body) {
+			size = len(body)
+		}
+		body = body[:size]
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("WAV fmt chunk is %d bytes, want at least 16", size)
+			}
+			if format := binary.LittleEndian.Uint16(body); format != 1 {
+				return nil, 0, fmt.Errorf("WAV format %d is not PCM", format)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits = int(binary.LittleEndian.Uint16(body[14:]))
+		case "data":
+			if channels == 0 {
+				return nil, 0, fmt.Errorf("WAV data chunk before fmt chunk")
+			}
+			if bits != 8 && bits != 16 {
+				return nil, 0, fmt.Errorf("WAV has %d-bit samples, want 8 or 16", bits)
+			}
+			frame := channels * bits / 8
+			samples := make([]float64, size/frame)
+			for i := range samples {
+				if bits == 8 {
+					samples[i] = (float64(body[i*frame]) - 128) / 128
+				} else {
+					samples[i] = float64(int16(binary.LittleEndian.Uint16(body[i*frame:]))) / 32768
+				}
+			}
+			return samples, rate, nil
+		}
+		p += 8 + size + size%2 // Chunks are padded to even length
+	}
+	return nil, 0, fmt.Errorf("WAV file has no data chunk")
+}
+
+// goertzel returns the power of samples at frequency freq.
+func goertzel(samples []float64, freq float64, rate int) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/float64(rate))
+	var s1, s2 float64
+	for _, x := range samples {
+		s1, s2 = x+coeff*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+// estimateTone finds the strongest frequency from 100 Hz to half the sample rate, to the
+// nearest 10 Hz. Rather than scan the whole recording at every frequency, it searches the band
+// over the loudest tenth of a second, then refines the peak over the loudest second, so the
+// cost does not grow with the length of the recording.
+func estimateTone(samples []float64, rate int) float64 {
+	strongest := func(window []float64, from, to float64) float64 {
+		best, bestPower := from, -1.0
+		for f := from; f <= to; f += 10 {
+			if p := goertzel(window, f, rate); p > bestPower {
+				best, bestPower = f, p
+			}
+		}
+		return best
+	}
+	nyquist := float64(rate) / 2
+	coarse := strongest(loudestWindow(samples, rate/10, rate/100), 100, math.Ceil(nyquist/10)*10-10)
+	return strongest(loudestWindow(samples, rate, rate/100), math.Max(100, coarse-50), math.Min(nyquist-1, coarse+50))
+}
+
+// loudestWindow returns the run of whole blocks, n samples or just under, with the most energy,
+// or all of samples if there are no more than n.
+func loudestWindow(samples []float64, n, block int) []float64 {
+	if len(samples) <= n || block < 1 {
+		return samples
+	}
+	nblocks := len(samples) / block
+	energy := make([]float64, nblocks+1) // energy[i] sums the first i blocks
+	for i := 0; i < nblocks; i++ {
+		e := 0.0
+		for _, x := range samples[i*block : (i+1)*block] {
+			e += x * x
+		}
+		energy[i+1] = energy[i] + e
+	}
+	span := n / block
+	best, bestEnergy := 0, -1.0
+	for i := 0; i+span <= nblocks; i++ {
+		if e := energy[i+span] - energy[i]; e > bestEnergy {
+			best, bestEnergy = i, e
+		}
+	}
+	return samples[best*block : (best+span)*block]
+}
+
+// twoMeans splits values into two clusters and returns the threshold between them and the
+// mean of the lower cluster.
+func twoMeans(values []float64) (threshold, low float64) {
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	threshold, low = (min+max)/2, min
+	for iter := 0; iter < 50; iter++ {
+		var sumLow, sumHigh float64
+		var nLow, nHigh int
+		for _, v := range values {
+			if v < threshold {
+				sumLow += v
+				nLow++
+			} else {
+				sumHigh += v
+				nHigh++
+			}
+		}
+		if nLow == 0 || nHigh == 0 {
+			break
+		}
+		low = sumLow / float64(nLow)
+		next := (low + sumHigh/float64(nHigh)) / 2
+		if next == threshold {
+			break
+		}
+		threshold = next
+	}
+	return threshold, low
+}
+
+// maxKeyWindow is the widest window decodeAudio tries, in 5ms blocks: half a dot at 3 words
+// per minute.
+const maxKeyWindow = 40
+
+// keyRun is a stretch of tone or silence, measured in blocks.
+type keyRun struct {
+	on     bool
+	length int
+}
+
+// keyRuns measures the tone at freq in windows of width blocks, advancing one block at a
+// time, and splits it into runs of tone and silence. Runs of a single block are taken as
+// noise and merged into their neighbours; leading and trailing silence is dropped.
+func keyRuns(samples []float64, rate int, freq float64, block, width int) []keyRun {
+	if len(samples) < (width+1)*block {
+		return nil
+	}
+	// Thresholding the amplitude, not the power, at half way between tone and silence puts
+	// the edges where a window half overlaps the tone.
+	level := make([]float64, len(samples)/block-width+1)
+	for i := range level {
+		level[i] = math.Sqrt(goertzel(samples[i*block:(i+width)*block], freq, rate))
+	}
+	threshold, low := twoMeans(level)
+	if threshold == low {
+		return nil // Silence, or a constant tone
+	}
+
+	var runs []keyRun
+	for _, l := range level {
+		on := l >= threshold
+		switch {
+		case len(runs) > 0 && runs[len(runs)-1].on == on:
+			runs[len(runs)-1].length++
+		case len(runs) > 1 && runs[len(runs)-1].length == 1:
+			runs = runs[:len(runs)-1]
+			runs[len(runs)-1].length += 2
+		default:
+			runs = append(runs, keyRun{on, 1})
+		}
+	}
+	for len(runs) > 0 && !runs[0].on {
+		runs = runs[1:]
+	}
+	for len(runs) > 0 && !runs[len(runs)-1].on {
+		runs = runs[:len(runs)-1]
+	}
+	return runs
+}
+
+// keyUnit estimates the length of a dot from runs. Dots and the gaps within letters are one
+// unit; dashes and the gaps between letters are three, and the gaps between words seven.
+// Lengths are clustered on a log scale so that word gaps do not pull dashes into the lower
+// cluster.
+func keyUnit(runs []keyRun) float64 {
+	var logs []float64
+	min, max := math.Inf(1), 0.0
+	for _, r := range runs {
+		n := math.Max(1, float64(r.length))
+		logs = append(logs, math.Log(n))
+		min, max = math.Min(min, n), math.Max(max, n)
+	}
+	if max < 2*min {
+		return min // All one length: assume dots with one-unit gaps
+	}
+	_, unit := twoMeans(logs)
+	return math.Exp(unit)
+}
+
+// decodeAudio recovers Morse text, in the form morseDecode reads, from audio samples. It finds
+// the tone frequency, measures the tone every 5ms, and separates tone from silence and
+// dots from dashes by clustering, so it needs neither the speed nor the frequency. The tone
+// is measured over windows of several widths, and the widest that stays within half the dot
+// length it gives is used, since it filters out the most noise.
+func decodeAudio(samples []float64, rate int) string {
+	block := rate / 200
+	if block < 1 {
+		return ""
+	}
+	freq := estimateTone(samples, rate)
+	var runs []keyRun
+	var unit float64
+	for width := 3; width <= maxKeyWindow; width = width * 3 / 2 {
+		wider := keyRuns(samples, rate, freq, block, width)
+		if len(wider) == 0 {
+			break
+		}
+		if u := keyUnit(wider); len(runs) == 0 || float64(width) <= u/2 {
+			runs, unit = wider, u
+		}
+	}
+	if len(runs) == 0 {
+		return ""
+	}
+
+	var b bytes.Buffer
+	for _, r := range runs {
+		units := float64(r.length) / unit
+		switch {
+		case r.on && units < 2:
+			b.WriteByte('.')
+		case r.on:
+			b.WriteByte('-')
+		case units >= 5:
+			b.WriteString(morseWordGap)
+		case units >= 2:
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+func runWAV(args []string) error {
+	fs := flag.NewFlagSet("wav", flag.ExitOnError)
+	decode := fs.String("decode", "", "WAV file to decode to text (default: encode text)")
+	output := fs.String("o", "morse.wav", "WAV file to write")
+	var tone morseTone
+	fs.IntVar(&tone.Rate, "rate", 8000, "sample rate in Hz")
+	fs.Float64Var(&tone.WPM, "wpm", 20, "sending speed in words per minute")
+	fs.Float64Var(&tone.Freq, "freq", 700, "tone frequency in Hz, from 100 to below half the rate")
+	fs.Float64Var(&tone.Noise, "noise", 0, "white noise level, relative to the tone")
+	seed := fs.Int64("seed", 1, "seed for the noise")
+	fs.Parse(args)
+
+	if *decode != "" {
+		f, err := os.Open(*decode)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		samples, rate, err := readWAV(f)
+		if err != nil {
+			return fmt.Errorf("%s: %s", *decode, err)
+		}
+		fmt.Println(morseDecode(decodeAudio(samples, rate)))
+		return nil
+	}
+
+	if tone.Rate < 2000 || tone.WPM <= 0 || tone.Freq < 100 || tone.Freq >= float64(tone.Rate)/2 {
+		return fmt.Errorf("need a rate of at least 2000 Hz, positive speed, and a tone from 100 Hz to below half the rate")
+	}
+	text, err := inputText(fs.Args())
+	if err != nil {
+		return err
+	}
+	morse, err := morseEncode(text)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeWAV(f, tone.samples(morse, rand.New(rand.NewSource(*seed))), tone.Rate); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestWAV(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 1, -1}
+	var buf bytes.Buffer
+	if err := writeWAV(&buf, samples, 8000); err != nil {
+		t.Fatalf("writeWAV failed: %s", err)
+	}
+	if buf.Len() != 44+2*len(samples) {
+		t.Errorf("WAV file is %d bytes, want %d", buf.Len(), 44+2*len(samples))
+	}
+	got, rate, err := readWAV(&buf)
+	if err != nil {
+		t.Fatalf("readWAV failed: %s", err)
+	}
+	if rate != 8000 || len(got) != len(samples) {
+		t.Fatalf("readWAV gave %d samples at %d Hz, want %d at 8000", len(got), rate, len(samples))
+	}
+	for i := range samples {
+		if d := got[i] - samples[i]; d > 0.001 || d < -0.001 {
+			t.Errorf("sample %d read as %f, want %f", i, got[i], samples[i])
+		}
+	}
+	if _, _, err := readWAV(bytes.NewBufferString("RIFF....AVI LIST")); err == nil {
+		t.Errorf("readWAV of a non-WAV file should raise error")
+	}
+}
+
+func TestMorseAudio(t *testing.T) {
+	m, _ := NewMachineFromKey(defaultKey, defaultAlphabet)
+	cipher := m.encipherMessage(englishSample[:60])
+	morse, _ := morseEncode(cipher)
+
+	var tones = []morseTone{
+		{Rate: 8000, WPM: 20, Freq: 700},
+		{Rate: 8000, WPM: 35, Freq: 1000, Noise: 0.5},
+		{Rate: 11025, WPM: 12, Freq: 550, Noise: 1},
+		{Rate: 8000, WPM: 20, Freq: 200, Noise: 1},
+		{Rate: 8000, WPM: 20, Freq: 2500, Noise: 1},
+	}
+	for _, tone := range tones {
+		var buf bytes.Buffer
+		writeWAV(&buf, tone.samples(morse, rand.New(rand.NewSource(1))), tone.Rate)
+		samples, rate, err := readWAV(&buf)
+		if err != nil {
+			t.Fatalf("readWAV failed: %s", err)
+		}
+		if f := estimateTone(samples, rate); f != tone.Freq {
+			t.Errorf("%+v: estimated tone %.0f Hz", tone, f)
+		}
+		heard := decodeAudio(samples, rate)
+		if heard != morse {
+			t.Errorf("%+v: decoded\n%s\nwant\n%s", tone, heard, morse)
+			continue
+		}
+		m, _ = NewMachineFromKey(defaultKey, defaultAlphabet)
+		if plain := m.decipherMessage(morseDecode(heard)); plain != englishSample[:60] {
+			t.Errorf("%+v: deciphered %s", tone, plain)
+		}
+	}
+
+	// Word gaps survive.
+	tone := morseTone{Rate: 8000, WPM: 25, Freq: 800, Noise: 0.8}
+	morse, _ = morseEncode("FNKIJY WO ISDO")
+	if heard := decodeAudio(tone.samples(morse, rand.New(rand.NewSource(2))), tone.Rate); heard != morse {
+		t.Errorf("decoded %q, want %q", heard, morse)
+	}
+
+	// A message of all dots, where speed cannot be told from dot and dash lengths.
+	tone = morseTone{Rate: 8000, WPM: 25, Freq: 800}
+	if heard := decodeAudio(tone.samples("... .. ....", nil), tone.Rate); heard != "... .. ...." {
+		t.Errorf("decoded dots as %q", heard)
+	}
+	if heard := decodeAudio(make([]float64, 1000), 8000); heard != "" {
+		t.Errorf("decoded silence as %q", heard)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// wiringStats describes the permutations of one switch, position by position. Positions and
+// levels are counted from 0. The differential statistics compare each position with the next
+// one the switch steps to, wrapping from the last position to the first.
+type wiringStats struct {
+	Name       string
+	Levels     int
+	Positions  int
+	Cycles     [][]int // Cycle lengths of each permutation, longest first
+	Fixed      []int   // Fixed points of each permutation
+	Repeats    [][]int // Groups of two or more positions with identical permutations
+	Agree      []int   // Levels mapped the same at each position and the next
+	DiffCycles [][]int // Cycle lengths of the change from each position to the next
+	DiffCounts []int   // How often the output moves by d levels, mod Levels, from a position to the next
+}
+
+// cycleType returns the lengths of the cycles of perm, longest first.
+func cycleType(perm []byte) []int {
+	seen := make([]bool, len(perm))
+	var lengths []int
+	for i := range perm {
+		n := 0
+		for j := i; !seen[j]; j = int(perm[j]) {
+			seen[j] = true
+			n++
+		}
+		if n > 0 {
+			lengths = append(lengths, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+	return lengths
+}
+
+// cycleString writes cycle lengths as, for example, "3+2+1".
+func cycleString(lengths []int) string {
+	s := make([]string, len(lengths))
+	for i, n := range lengths {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, "+")
+}
+
+// analyzeWiring computes wiringStats for the permutations in w, one per position.
+func analyzeWiring(name string, w switchData) wiringStats {
+	n := len(w[0])
+	ws := wiringStats{Name: name, Levels: n, Positions: len(w), DiffCounts: make([]int, n)}
+	rows := make(map[string][]int)
+	var order []string
+	for i, perm := range w {
+		ws.Cycles = append(ws.Cycles, cycleType(perm))
+		fixed := 0
+		for j, p := range perm {
+			if int(p) == j {
+				fixed++
+			}
+		}
+		ws.Fixed = append(ws.Fixed, fixed)
+		key := string(perm)
+		if rows[key] == nil {
+			order = append(order, key)
+		}
+		rows[key] = append(rows[key], i)
+
+		// The change from this position to the next: where the output of each level at this
+		// position goes at the next.
+		next := w[(i+1)%len(w)]
+		agree := 0
+		change := make([]byte, n)
+		for j := range perm {
+			if perm[j] == next[j] {
+				agree++
+			}
+			ws.DiffCounts[(int(next[j])-int(perm[j])+n)%n]++
+			change[perm[j]] = next[j]
+		}
+		ws.Agree = append(ws.Agree, agree)
+		ws.DiffCycles = append(ws.DiffCycles, cycleType(change))
+	}
+	for _, key := range order {
+		if len(rows[key]) > 1 {
+			ws.Repeats = append(ws.Repeats, rows[key])
+		}
+	}
+	return ws
+}
+
+// writeReport prints ws in readable form. Positions are 1-25, as in keys; for comparison, a
+// random permutation has on average one fixed point, and a random pair of permutations agrees
+// on one level.
+func (ws wiringStats) writeReport(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d positions of %d levels\n", ws.Name, ws.Positions, ws.Levels)
+	fmt.Fprintf(w, "  %-8s %-20s %5s  %-20s %5s\n", "position", "cycles", "fixed", "change to next", "agree")
+	totalFixed, totalAgree := 0, 0
+	types := make(map[string]int)
+	for i := 0; i < ws.Positions; i++ {
+		fmt.Fprintf(w, "  %-8d %-20s %5d  %-20s %5d\n", i+1, cycleString(ws.Cycles[i]), ws.Fixed[i],
+			cycleString(ws.DiffCycles[i]), ws.Agree[i])
+		totalFixed += ws.Fixed[i]
+		totalAgree += ws.Agree[i]
+		types[cycleString(ws.Cycles[i])]++
+	}
+	fmt.Fprintf(w, "  mean fixed points %.2f, mean agreement with next position %.2f (random: 1)\n",
+		float64(totalFixed)/float64(ws.Positions), float64(totalAgree)/float64(ws.Positions))
+
+	var names []string
+	for t := range types {
+		names = append(names, t)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(w, "  cycle types:")
+	for _, t := range names {
+		fmt.Fprintf(w, " %s (x%d)", t, types[t])
+	}
+	fmt.Fprintln(w)
+
+	if len(ws.Repeats) == 0 {
+		fmt.Fprintf(w, "  no permutation repeats\n")
+	}
+	for _, group := range ws.Repeats {
+		pos := make([]string, len(group))
+		for i, p := range group {
+			pos[i] = strconv.Itoa(p + 1)
+		}
+		fmt.Fprintf(w, "  identical permutations at positions %s\n", strings.Join(pos, ", "))
+	}
+
+	// Chi-squared of the output shifts against a uniform spread; about Levels-1 is expected.
+	total := ws.Positions * ws.Levels
+	expect := float64(total) / float64(ws.Levels)
+	chi2 := 0.0
+	for _, c := range ws.DiffCounts {
+		chi2 += (float64(c) - expect) * (float64(c) - expect) / expect
+	}
+	fmt.Fprintf(w, "  output shift from one position to the next, 0 to %d:", ws.Levels-1)
+	for _, c := range ws.DiffCounts {
+		fmt.Fprintf(w, " %d", c)
+	}
+	fmt.Fprintf(w, "\n  chi-squared against uniform %.1f with %d degrees of freedom\n", chi2, ws.Levels-1)
+}
+
+// readWiring reads switch wiring from r: one line per position, each a permutation of 1 to n
+// separated by spaces or commas. Blank lines and lines starting with '#' are ignored. The name
+// is used in errors.
+func readWiring(name string, r io.Reader) (switchData, error) {
+	var p [][]byte
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(c rune) bool { return c == ' ' || c == ',' || c == '\t' })
+		row := make([]byte, len(fields))
+		seen := make([]bool, len(fields)+1)
+		for i, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil || v < 1 || v > len(fields) || seen[v] {
+				return nil, fmt.Errorf("%s:%d: %q is not a permutation of 1 to %d", name, lineno, line, len(fields))
+			}
+			seen[v] = true
+			row[i] = byte(v)
+		}
+		if len(p) > 0 && len(row) != len(p[0]) {
+			return nil, fmt.Errorf("%s:%d: %d levels, want %d as in the first line", name, lineno, len(row), len(p[0]))
+		}
+		p = append(p, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%s: no wiring", name)
+	}
+	return datamaker(p), nil
+}
+
+func runWiring(args []string) error {
+	fs := flag.NewFlagSet("wiring", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: purple wiring [file ...]\n\n"+
+			"Report on the historical switch wiring, or on custom wiring read from files.\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var stats []wiringStats
+	if fs.NArg() == 0 {
+		for _, s := range []struct {
+			name string
+			sw   *Switch
+		}{{"sixes", sixesSwitch}, {"twenties 1", twenties1}, {"twenties 2", twenties2}, {"twenties 3", twenties3}} {
+			stats = append(stats, analyzeWiring(s.name, s.sw.decipherWiring))
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		w, err := readWiring(filepath.Base(name), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		stats = append(stats, analyzeWiring(name, w))
+	}
+	for i, ws := range stats {
+		if i > 0 {
+			fmt.Println()
+		}
+		ws.writeReport(os.Stdout)
+	}
+	return nil
+}
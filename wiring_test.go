@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCycleType(t *testing.T) {
+	var tests = []struct {
+		perm []byte
+		want []int
+	}{
+		{[]byte{0, 1, 2}, []int{1, 1, 1}},
+		{[]byte{1, 0, 2, 4, 3, 5}, []int{2, 2, 1, 1}},
+		{[]byte{1, 2, 3, 0}, []int{4}},
+		{[]byte{2, 0, 1, 4, 3}, []int{3, 2}},
+	}
+	for _, test := range tests {
+		if got := cycleType(test.perm); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("cycleType(%v) = %v, want %v", test.perm, got, test.want)
+		}
+	}
+	if s := cycleString([]int{3, 2, 1}); s != "3+2+1" {
+		t.Errorf("cycleString gave %q", s)
+	}
+}
+
+func TestAnalyzeWiring(t *testing.T) {
+	ws := analyzeWiring("sixes", sixesSwitch.decipherWiring)
+	if ws.Positions != 25 || ws.Levels != 6 {
+		t.Errorf("sixes has %d positions of %d levels, want 25 of 6", ws.Positions, ws.Levels)
+	}
+	// Position 0 is 2 1 3 5 4 6: (1 2)(3)(4 5)(6).
+	if !reflect.DeepEqual(ws.Cycles[0], []int{2, 2, 1, 1}) || ws.Fixed[0] != 2 {
+		t.Errorf("sixes position 0 has cycles %v and %d fixed points", ws.Cycles[0], ws.Fixed[0])
+	}
+	found := false
+	for _, group := range ws.Repeats {
+		if reflect.DeepEqual(group, []int{4, 7}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("sixes repeats %v do not include positions 4 and 7", ws.Repeats)
+	}
+	// Positions 0 and 1 are 2 1 3 5 4 6 and 6 3 5 2 1 4, which agree nowhere.
+	if ws.Agree[0] != 0 {
+		t.Errorf("sixes positions 0 and 1 agree on %d levels, want 0", ws.Agree[0])
+	}
+	sum := 0
+	for _, c := range ws.DiffCounts {
+		sum += c
+	}
+	if sum != 25*6 || ws.DiffCounts[0] != sumInts(ws.Agree) {
+		t.Errorf("DiffCounts %v should total %d with %d shifts of 0", ws.DiffCounts, 25*6, sumInts(ws.Agree))
+	}
+
+	for _, s := range []*Switch{twenties1, twenties2, twenties3} {
+		ws := analyzeWiring("twenties", s.decipherWiring)
+		for i, c := range ws.Cycles {
+			if sumInts(c) != 20 || sumInts(ws.DiffCycles[i]) != 20 {
+				t.Errorf("twenties position %d has cycles %v and change %v, which should cover 20 levels", i, c, ws.DiffCycles[i])
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	ws.writeReport(&buf)
+	for _, want := range []string{"sixes: 25 positions of 6 levels", "identical permutations at positions 5, 8",
+		"2+2+1+1", "chi-squared against uniform"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func sumInts(a []int) int {
+	n := 0
+	for _, v := range a {
+		n += v
+	}
+	return n
+}
+
+func TestReadWiring(t *testing.T) {
+	w, err := readWiring("test", strings.NewReader("# A custom switch\n1 2 3\n\n2,3,1\n3 1 2\n"))
+	if err != nil {
+		t.Fatalf("readWiring failed: %s", err)
+	}
+	if want := (switchData{{0, 1, 2}, {1, 2, 0}, {2, 0, 1}}); !reflect.DeepEqual(w, want) {
+		t.Errorf("readWiring gave %v, want %v", w, want)
+	}
+	ws := analyzeWiring("test", w)
+	if ws.Fixed[0] != 3 || len(ws.Repeats) != 0 || !reflect.DeepEqual(ws.DiffCounts, []int{0, 9, 0}) {
+		t.Errorf("analyzeWiring gave %+v", ws)
+	}
+
+	for _, bad := range []string{"", "1 2 2", "1 2 4", "1 2 x", "1 2 3\n1 2"} {
+		if _, err := readWiring("bad", strings.NewReader(bad)); err == nil {
+			t.Errorf("readWiring(%q) should raise error", bad)
+		}
+	}
+}